@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"censei/config"
 	"censei/logging"
@@ -19,6 +20,8 @@ type CensysClient struct {
 	APISecret string
 	Config    *config.Config
 	Logger    *logging.Logger
+
+	resultCount int // Censys results parsed before expansion to service URLs
 }
 
 // NewCensysClient creates a new client for Censys API interactions
@@ -41,8 +44,8 @@ func (c *CensysClient) ExecuteQuery(query, outputDir string) (string, error) {
 
 	// Build command with config values
 	c.Logger.Debug("Creating censys command with API credentials and config parameters")
-	cmd := exec.Command(
-		"censys", "search",
+	args := []string{
+		"search",
 		"--api-id", c.APIID,
 		"--api-secret", c.APISecret,
 		"--page", strconv.Itoa(c.Config.LegacyPages),
@@ -51,8 +54,14 @@ func (c *CensysClient) ExecuteQuery(query, outputDir string) (string, error) {
 		"--sort-order", c.Config.LegacySortOrder,
 		"--virtual-hosts", c.Config.LegacyVirtualHosts,
 		"--output", outputPath,
-		query,
-	)
+	}
+
+	// Extra flags from legacy_extra_args go before the query (validated with the config)
+	args = append(args, c.Config.LegacyExtraArgs...)
+	args = append(args, query)
+
+	c.Logger.Debug("Censys command: censys %s", strings.Join(logging.RedactArgs(args), " "))
+	cmd := exec.Command("censys", args...)
 
 	// Create a buffer to capture output
 	var stdout, stderr bytes.Buffer
@@ -146,6 +155,8 @@ func (c *CensysClient) ExtractHostsFromResults(jsonPath string) ([]Host, error)
 		c.Logger.Debug("Successfully parsed JSON as array with %d results", len(results))
 	}
 
+	c.resultCount += len(results)
+
 	// Extract hosts - pre-allocate with estimated capacity
 	// Estimate: results × average services per result (typically 2-5)
 	estimatedHosts := len(results) * 3
@@ -172,6 +183,22 @@ func (c *CensysClient) ExtractHostsFromResults(jsonPath string) ([]Host, error)
 
 		// Extract each HTTP service
 		for j, service := range servicesToProcess {
+			// FTP and SMB services are listed separately when enabled
+			if (service.ServiceName == "FTP" && c.Config.EnableFTP) || (service.ServiceName == "SMB" && c.Config.EnableSMB) {
+				protocol := strings.ToLower(service.ServiceName)
+				host := Host{
+					BaseAddress: baseAddress,
+					IP:          result.IP,
+					Port:        service.Port,
+					Protocol:    protocol,
+					URL:         formatHostURL(protocol, baseAddress, service.Port),
+					ASN:         result.AutonomousSystem.ASN,
+				}
+				c.Logger.Debug("Created %s host #%d.%d: %s", service.ServiceName, i, j, host.URL)
+				hosts = append(hosts, host)
+				continue
+			}
+
 			// Only process HTTP services
 			if service.ServiceName != "HTTP" && service.ServiceName != "HTTPS" {
 				continue
@@ -194,6 +221,7 @@ func (c *CensysClient) ExtractHostsFromResults(jsonPath string) ([]Host, error)
 				Port:        service.Port,
 				Protocol:    protocol,
 				URL:         fmt.Sprintf("%s://%s:%d", protocol, addressForURL, service.Port),
+				ASN:         result.AutonomousSystem.ASN,
 			}
 
 			// Special case for standard ports
@@ -209,6 +237,14 @@ func (c *CensysClient) ExtractHostsFromResults(jsonPath string) ([]Host, error)
 		}
 	}
 
+	hosts, _ = validateHosts(hosts, c.Logger)
+	hosts, _ = dedupeHosts(hosts, make(map[string]bool, len(hosts)), c.Logger)
+
 	c.Logger.Debug("Extracted %d hosts from Censys results", len(hosts))
 	return hosts, nil
 }
+
+// ResultCount returns the number of Censys results parsed so far, before expansion to service URLs
+func (c *CensysClient) ResultCount() int {
+	return c.resultCount
+}
@@ -3,9 +3,15 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"censei/config"
 	"censei/logging"
@@ -13,6 +19,7 @@ import (
 	censyssdkgo "github.com/censys/censys-sdk-go"
 	"github.com/censys/censys-sdk-go/models/components"
 	"github.com/censys/censys-sdk-go/models/operations"
+	"github.com/censys/censys-sdk-go/models/sdkerrors"
 )
 
 // CensysV3Client handles interactions with the Censys Platform API v3
@@ -20,6 +27,9 @@ type CensysV3Client struct {
 	sdk    *censyssdkgo.SDK
 	Config *config.Config
 	Logger *logging.Logger
+
+	resultCount int             // Censys results parsed before expansion to service URLs
+	seenURLs    map[string]bool // Host URLs extracted so far, see dedupeHosts
 }
 
 // NewCensysV3Client creates a new client for Censys Platform API v3 interactions
@@ -31,7 +41,7 @@ func NewCensysV3Client(bearerToken string, cfg *config.Config, logger *logging.L
 
 	// Add organization ID if provided
 	if cfg.OrganizationID != "" {
-		logger.Debug("Using Organization ID: %s", cfg.OrganizationID)
+		logger.Debug("Using Organization ID from config")
 		sdkOpts = append(sdkOpts, censyssdkgo.WithOrganizationID(cfg.OrganizationID))
 	}
 
@@ -45,21 +55,92 @@ func NewCensysV3Client(bearerToken string, cfg *config.Config, logger *logging.L
 	}, nil
 }
 
+// maxRateLimitRetries limits how often a rate-limited page is retried when pacing is enabled
+const maxRateLimitRetries = 5
+
+// Retry backoff defaults and limits for transient Platform API v3 errors
+const (
+	defaultRetryBaseDelayMS = 1000
+	maxRetryDelay           = 60 * time.Second
+)
+
+// isRetryableV3Error reports whether a failed search may succeed when repeated
+// Rate limits, server errors and network failures are retryable; auth and query errors are not
+func isRetryableV3Error(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var authErr *sdkerrors.AuthenticationError
+	if errors.As(err, &authErr) {
+		return false
+	}
+
+	var errorModel *sdkerrors.ErrorModel
+	if errors.As(err, &errorModel) {
+		return errorModel.Status != nil && isRetryableStatus(int(*errorModel.Status))
+	}
+
+	var sdkErr *sdkerrors.SDKError
+	if errors.As(err, &sdkErr) {
+		return isRetryableStatus(sdkErr.StatusCode)
+	}
+
+	// Connection resets, timeouts and other transport errors
+	return true
+}
+
+// isRetryableStatus checks for HTTP status codes of transient failures
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// retryDelay returns the exponential backoff for a retry attempt (base, 2×base, 4×base, ...)
+// with up to 50% random jitter so parallel scans don't retry in lockstep
+func retryDelay(baseDelayMS int, attempt int) time.Duration {
+	if baseDelayMS <= 0 {
+		baseDelayMS = defaultRetryBaseDelayMS
+	}
+
+	delay := time.Duration(baseDelayMS) * time.Millisecond
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
 // ExecuteQuery runs a Censys search query and saves results to a JSON file
-func (c *CensysV3Client) ExecuteQuery(query, outputDir string) (string, error) {
+// Cancelling ctx stops pagination; the results fetched so far are still saved
+func (c *CensysV3Client) ExecuteQuery(ctx context.Context, query, outputDir string) (string, error) {
+	return c.ExecuteQueryPipelined(ctx, query, outputDir, nil)
+}
+
+// ExecuteQueryPipelined runs a Censys search query like ExecuteQuery and additionally
+// passes the hosts of every completed page to onPage, so crawling can start before
+// later pages are fetched. onPage may be nil.
+func (c *CensysV3Client) ExecuteQueryPipelined(ctx context.Context, query, outputDir string, onPage func([]Host)) (string, error) {
 	// Create output filename
 	outputPath := filepath.Join(outputDir, "censys_results.json")
 
 	c.Logger.Info("Executing Censys Platform API v3 query: %s", query)
 	c.Logger.Debug("Output will be saved to: %s", outputPath)
 
-	ctx := context.Background()
+	// Page size from config, defaulting to the API maximum of 100
+	pageSize := c.Config.V3PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	c.Logger.Debug("Using page size: %d", pageSize)
 
 	// Prepare search request
 	searchRequest := operations.V3GlobaldataSearchQueryRequest{
 		SearchQueryInputBody: components.SearchQueryInputBody{
 			Query:    query,
-			PageSize: censyssdkgo.Pointer[int64](100), // Max results per page
+			PageSize: censyssdkgo.Pointer(int64(pageSize)),
 		},
 	}
 
@@ -75,20 +156,76 @@ func (c *CensysV3Client) ExecuteQuery(query, outputDir string) (string, error) {
 
 	c.Logger.Debug("Starting paginated search with max results: %d", c.Config.V3MaxResults)
 
+	// Optional pacing between page requests to stay below API rate limits
+	pacer := newPagePacer(c.Config.V3MinPageDelayMS, c.Config.V3MaxPageDelayMS, c.Logger)
+	rateLimitRetries := 0
+	transientRetries := 0
+	requested := false
+	pageNumber := 0
+
 	// Paginate through results
 	for {
+		// Stop paginating on shutdown and keep the pages fetched so far
+		if ctx.Err() != nil {
+			c.Logger.Info("Platform API v3 query interrupted after %d results, saving partial results", totalFetched)
+			break
+		}
+
 		// Set page token if we have one from previous iteration
 		if pageToken != nil {
 			searchRequest.SearchQueryInputBody.PageToken = pageToken
 			c.Logger.Debug("Fetching next page with token: %s", *pageToken)
 		}
 
+		// Space out requests after the first one (next pages and rate limit retries)
+		if requested {
+			pacer.wait(ctx)
+		}
+		requested = true
+
 		// Execute search
 		response, err := c.sdk.GlobalData.Search(ctx, searchRequest)
 		if err != nil {
+			// Cancelled requests are handled at the top of the loop
+			if ctx.Err() != nil {
+				continue
+			}
+
+			// Back off and retry the same page when rate limited
+			var sdkErr *sdkerrors.SDKError
+			if pacer.enabled() && errors.As(err, &sdkErr) && sdkErr.StatusCode == http.StatusTooManyRequests &&
+				rateLimitRetries < maxRateLimitRetries {
+				rateLimitRetries++
+				var headers http.Header
+				if sdkErr.RawResponse != nil {
+					headers = sdkErr.RawResponse.Header
+				}
+				pacer.backoff(headers)
+				c.Logger.Info("Platform API v3 rate limit reached, retrying page in %v (%d/%d)", pacer.delay, rateLimitRetries, maxRateLimitRetries)
+				continue
+			}
+
+			// Retry transient errors (rate limits, 5xx, network) with exponential backoff
+			if transientRetries < c.Config.V3MaxRetries && isRetryableV3Error(err) {
+				transientRetries++
+				delay := retryDelay(c.Config.V3RetryBaseDelayMS, transientRetries)
+				c.Logger.Info("Platform API v3 search failed: %v - retry %d/%d in %v", err, transientRetries, c.Config.V3MaxRetries, delay.Round(time.Millisecond))
+				sleepContext(ctx, delay)
+				continue
+			}
+
 			c.Logger.Error("Platform API v3 search failed: %v", err)
 			return "", fmt.Errorf("platform API v3 search error: %w", err)
 		}
+		rateLimitRetries = 0
+		transientRetries = 0
+		pacer.observe(http.Header(response.Headers))
+		pageNumber++
+
+		// Keep the unmodified page for diagnosing extraction problems
+		if c.Config.SaveRawPages {
+			c.saveRawPage(outputDir, pageNumber, response.HTTPMeta.Response)
+		}
 
 		// Check for API errors in response
 		if response.ResponseEnvelopeSearchQueryResponse == nil {
@@ -105,6 +242,17 @@ func (c *CensysV3Client) ExecuteQuery(query, outputDir string) (string, error) {
 			// Append hits directly
 			allResults = append(allResults, response.ResponseEnvelopeSearchQueryResponse.Result.Hits...)
 
+			// Hand the hosts of this page to the crawler right away
+			if onPage != nil {
+				pageHosts, err := c.hitsToHosts(response.ResponseEnvelopeSearchQueryResponse.Result.Hits)
+				if err != nil {
+					c.Logger.Error("Failed to extract hosts from page: %v", err)
+				} else {
+					c.Logger.Debug("Passing %d hosts from page to crawler", len(pageHosts))
+					onPage(pageHosts)
+				}
+			}
+
 			totalFetched += resultsCount
 			c.Logger.Debug("Fetched %d results (total: %d)", resultsCount, totalFetched)
 		}
@@ -127,7 +275,9 @@ func (c *CensysV3Client) ExecuteQuery(query, outputDir string) (string, error) {
 		pageToken = &nextToken
 	}
 
-	c.Logger.Info("Platform API v3 query completed successfully, fetched %d results", totalFetched)
+	if ctx.Err() == nil {
+		c.Logger.Info("Platform API v3 query completed successfully, fetched %d results", totalFetched)
+	}
 
 	// Save results to JSON file
 	c.Logger.Debug("Saving results to file: %s", outputPath)
@@ -156,6 +306,33 @@ func (c *CensysV3Client) ExecuteQuery(query, outputDir string) (string, error) {
 	return outputPath, nil
 }
 
+// saveRawPage writes the raw JSON body of a result page to outputDir/pages/page_N.json
+func (c *CensysV3Client) saveRawPage(outputDir string, pageNumber int, resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		c.Logger.Debug("No raw response available for page %d", pageNumber)
+		return
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.Logger.Error("Failed to read raw response of page %d: %v", pageNumber, err)
+		return
+	}
+
+	pagesDir := filepath.Join(outputDir, "pages")
+	if err := os.MkdirAll(pagesDir, 0755); err != nil {
+		c.Logger.Error("Failed to create pages directory: %v", err)
+		return
+	}
+
+	pagePath := filepath.Join(pagesDir, fmt.Sprintf("page_%d.json", pageNumber))
+	if err := os.WriteFile(pagePath, body, 0644); err != nil {
+		c.Logger.Error("Failed to save raw page %d: %v", pageNumber, err)
+		return
+	}
+	c.Logger.Debug("Saved raw page %d to %s (%d bytes)", pageNumber, pagePath, len(body))
+}
+
 // ExtractHostsFromResults processes Censys JSON results and extracts hosts for crawling
 func (c *CensysV3Client) ExtractHostsFromResults(jsonPath string) ([]Host, error) {
 	c.Logger.Info("Extracting hosts from Censys Platform API v3 results")
@@ -181,6 +358,37 @@ func (c *CensysV3Client) ExtractHostsFromResults(jsonPath string) ([]Host, error
 
 	c.Logger.Debug("Successfully parsed JSON with %d results", len(results))
 
+	hosts := c.extractHosts(results)
+	c.Logger.Debug("Extracted %d hosts from Censys Platform API v3 results", len(hosts))
+	return hosts, nil
+}
+
+// ResultCount returns the number of Censys results parsed so far, before expansion to service URLs
+// In pipelined mode this grows with every page
+func (c *CensysV3Client) ResultCount() int {
+	return c.resultCount
+}
+
+// hitsToHosts extracts hosts from a single page of search hits
+// The hits are round-tripped through JSON so they share the generic extraction path
+func (c *CensysV3Client) hitsToHosts(hits []components.SearchQueryHit) ([]Host, error) {
+	data, err := json.Marshal(hits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode search hits: %w", err)
+	}
+
+	var results []map[string]interface{}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode search hits: %w", err)
+	}
+
+	return c.extractHosts(results), nil
+}
+
+// extractHosts converts parsed Platform API v3 results into crawlable hosts
+func (c *CensysV3Client) extractHosts(results []map[string]interface{}) []Host {
+	c.resultCount += len(results)
+
 	// Extract hosts - pre-allocate with estimated capacity
 	// Estimate: results × average services/endpoints per result (typically 2-5)
 	estimatedHosts := len(results) * 3
@@ -268,6 +476,14 @@ func (c *CensysV3Client) ExtractHostsFromResults(jsonPath string) ([]Host, error
 			}
 		}
 
+		// Autonomous system number from resource → autonomous_system → asn (used for host priority)
+		asn := 0
+		if asInterface, ok := resourceMap["autonomous_system"].(map[string]interface{}); ok {
+			if asnValue, ok := asInterface["asn"].(float64); ok {
+				asn = int(asnValue)
+			}
+		}
+
 		// Process services - it's an array directly in resource → services
 		servicesInterface, ok := resourceMap["services"]
 		if !ok {
@@ -375,6 +591,7 @@ func (c *CensysV3Client) ExtractHostsFromResults(jsonPath string) ([]Host, error
 						Port:        port,
 						Protocol:    protocol,
 						URL:         fmt.Sprintf("%s://%s:%d", protocol, addressForURL, port),
+						ASN:         asn,
 					}
 
 					// Special case for standard ports
@@ -395,6 +612,18 @@ func (c *CensysV3Client) ExtractHostsFromResults(jsonPath string) ([]Host, error
 
 				// Check protocol field (v3 API uses "protocol")
 				protocol, ok := service["protocol"].(string)
+				if ok && ((protocol == "FTP" && c.Config.EnableFTP) || (protocol == "SMB" && c.Config.EnableSMB)) {
+					defaultPort := 21
+					if protocol == "SMB" {
+						defaultPort = 445
+					}
+					if host, ok := serviceHost(strings.ToLower(protocol), defaultPort, service["port"], baseAddress, ip); ok {
+						host.ASN = asn
+						c.Logger.Debug("Created %s host #%d.%d: %s", protocol, i, j, host.URL)
+						hosts = append(hosts, host)
+					}
+					continue
+				}
 				if !ok || (protocol != "HTTP" && protocol != "HTTPS") {
 					c.Logger.Debug("Service is not HTTP/HTTPS - protocol: %s", protocol)
 					continue
@@ -435,6 +664,7 @@ func (c *CensysV3Client) ExtractHostsFromResults(jsonPath string) ([]Host, error
 					Port:        port,
 					Protocol:    httpProtocol,
 					URL:         fmt.Sprintf("%s://%s:%d", httpProtocol, addressForURL, port),
+					ASN:         asn,
 				}
 
 				// Special case for standard ports
@@ -451,6 +681,11 @@ func (c *CensysV3Client) ExtractHostsFromResults(jsonPath string) ([]Host, error
 		}
 	}
 
-	c.Logger.Debug("Extracted %d hosts from Censys Platform API v3 results", len(hosts))
-	return hosts, nil
+	hosts, _ = validateHosts(hosts, c.Logger)
+	// URLs are remembered across pages, so pipelined mode does not crawl a host twice either
+	if c.seenURLs == nil {
+		c.seenURLs = make(map[string]bool)
+	}
+	hosts, _ = dedupeHosts(hosts, c.seenURLs, c.Logger)
+	return hosts
 }
@@ -1,9 +1,297 @@
 package api
 
-import "net"
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"censei/logging"
+)
 
 // isIPv6 checks if the given string is an IPv6 address
 func isIPv6(ipStr string) bool {
 	ip := net.ParseIP(ipStr)
 	return ip != nil && ip.To4() == nil
 }
+
+// formatHostURL builds the crawl URL for an address, protocol and port
+// Standard ports (80/443, 21 for FTP, 445 for SMB) are omitted and IPv6 addresses are bracketed
+func formatHostURL(protocol, address string, port int) string {
+	// Format address for URL (add brackets for IPv6)
+	addressForURL := address
+	if isIPv6(address) {
+		addressForURL = fmt.Sprintf("[%s]", address)
+	}
+
+	// Special case for standard ports
+	if (protocol == "ftp" && port == 21) || (protocol == "smb" && port == 445) {
+		return fmt.Sprintf("%s://%s", protocol, addressForURL)
+	}
+	switch port {
+	case 443:
+		return fmt.Sprintf("https://%s", addressForURL)
+	case 80:
+		return fmt.Sprintf("http://%s", addressForURL)
+	}
+
+	return fmt.Sprintf("%s://%s:%d", protocol, addressForURL, port)
+}
+
+// serviceHost builds an FTP or SMB host from a v3 service port value (defaults to defaultPort)
+func serviceHost(protocol string, defaultPort int, portValue interface{}, baseAddress, ip string) (Host, bool) {
+	port := defaultPort
+	switch v := portValue.(type) {
+	case float64:
+		port = int(v)
+	case int:
+		port = v
+	case nil:
+	default:
+		return Host{}, false
+	}
+
+	return Host{
+		BaseAddress: baseAddress,
+		IP:          ip,
+		Port:        port,
+		Protocol:    protocol,
+		URL:         formatHostURL(protocol, baseAddress, port),
+	}, true
+}
+
+// validateHosts drops hosts whose URL does not parse or has no host part
+// Malformed reverse-DNS names would otherwise fail later with cryptic crawler errors
+// Returns the valid hosts and the number of dropped hosts
+func validateHosts(hosts []Host, logger *logging.Logger) ([]Host, int) {
+	valid := hosts[:0]
+	invalid := 0
+
+	for _, host := range hosts {
+		parsedURL, err := url.Parse(host.URL)
+		if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+			logger.Error("WARNING: Skipping host with invalid URL %q (base address %q): %v", host.URL, host.BaseAddress, err)
+			invalid++
+			continue
+		}
+		valid = append(valid, host)
+	}
+
+	if invalid > 0 {
+		logger.Info("Dropped %d hosts with invalid URLs", invalid)
+	}
+	return valid, invalid
+}
+
+// dedupeHosts drops hosts whose URL was already seen, keeping the first occurrence and the order
+// Identical URLs appear when matched_services and services overlap or results share an IP
+// seen carries the URLs across calls (e.g. result pages); returns the hosts and the number dropped
+func dedupeHosts(hosts []Host, seen map[string]bool, logger *logging.Logger) ([]Host, int) {
+	unique := hosts[:0]
+	duplicates := 0
+
+	for _, host := range hosts {
+		if seen[host.URL] {
+			logger.Debug("Skipping duplicate host URL: %s", host.URL)
+			duplicates++
+			continue
+		}
+		seen[host.URL] = true
+		unique = append(unique, host)
+	}
+
+	if duplicates > 0 {
+		logger.Info("Collapsed %d duplicate host URLs", duplicates)
+	}
+	return unique, duplicates
+}
+
+// AddIPHosts adds an IP-based host entry for every host that was resolved to a DNS name
+// The raw IP sometimes serves a different vhost than the name, so both are scanned
+// Returns the extended host list and the number of IP-based hosts that were added
+func AddIPHosts(hosts []Host) ([]Host, int) {
+	// Track existing URLs so IP variants that are already present are not duplicated
+	seen := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		seen[host.URL] = true
+	}
+
+	added := 0
+	result := hosts
+	for _, host := range hosts {
+		if host.IP == "" || host.BaseAddress == host.IP {
+			continue
+		}
+
+		ipURL := formatHostURL(host.Protocol, host.IP, host.Port)
+		if seen[ipURL] {
+			continue
+		}
+		seen[ipURL] = true
+
+		result = append(result, Host{
+			BaseAddress: host.IP,
+			IP:          host.IP,
+			Port:        host.Port,
+			Protocol:    host.Protocol,
+			URL:         ipURL,
+			ASN:         host.ASN,
+		})
+		added++
+	}
+
+	return result, added
+}
+
+// FilterHostsByIPVersion keeps only hosts whose IP matches an enabled address family
+// Hosts without a parseable IP are kept since their family is unknown
+// Returns the filtered host list and the number of hosts that were dropped
+func FilterHostsByIPVersion(hosts []Host, scanIPv4, scanIPv6 bool) ([]Host, int) {
+	if scanIPv4 && scanIPv6 {
+		return hosts, 0
+	}
+
+	result := make([]Host, 0, len(hosts))
+	for _, host := range hosts {
+		if net.ParseIP(host.IP) != nil {
+			if ipv6 := isIPv6(host.IP); (ipv6 && !scanIPv6) || (!ipv6 && !scanIPv4) {
+				continue
+			}
+		}
+		result = append(result, host)
+	}
+
+	return result, len(hosts) - len(result)
+}
+
+// FilterPrivateHosts drops hosts whose IP is private (RFC 1918, RFC 4193), loopback,
+// link-local or unspecified. Hosts without an IP are checked by their address if it is one
+// Returns the filtered host list and the dropped hosts
+func FilterPrivateHosts(hosts []Host) ([]Host, []Host) {
+	result := make([]Host, 0, len(hosts))
+	var dropped []Host
+	for _, host := range hosts {
+		address := host.IP
+		if address == "" {
+			address = host.BaseAddress
+		}
+		if ip := net.ParseIP(address); ip != nil && (ip.IsPrivate() || ip.IsLoopback() ||
+			ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()) {
+			dropped = append(dropped, host)
+			continue
+		}
+		result = append(result, host)
+	}
+
+	return result, dropped
+}
+
+// FilterHostsByName keeps only hosts whose name (BaseAddress) matches pattern
+// Hosts without a DNS name are matched by their IP
+// Returns the filtered host list and the number of hosts that were dropped
+func FilterHostsByName(hosts []Host, pattern *regexp.Regexp) ([]Host, int) {
+	result := make([]Host, 0, len(hosts))
+	for _, host := range hosts {
+		if pattern.MatchString(host.BaseAddress) {
+			result = append(result, host)
+		}
+	}
+
+	return result, len(hosts) - len(result)
+}
+
+// FilterHostsByPort drops hosts whose port is not in allowedPorts (if non-empty) or is in blockedPorts
+// Returns the filtered host list and the number of hosts that were dropped
+func FilterHostsByPort(hosts []Host, allowedPorts, blockedPorts []int) ([]Host, int) {
+	if len(allowedPorts) == 0 && len(blockedPorts) == 0 {
+		return hosts, 0
+	}
+
+	allowed := make(map[int]bool, len(allowedPorts))
+	for _, port := range allowedPorts {
+		allowed[port] = true
+	}
+	blocked := make(map[int]bool, len(blockedPorts))
+	for _, port := range blockedPorts {
+		blocked[port] = true
+	}
+
+	result := make([]Host, 0, len(hosts))
+	for _, host := range hosts {
+		if (len(allowed) > 0 && !allowed[host.Port]) || blocked[host.Port] {
+			continue
+		}
+		result = append(result, host)
+	}
+
+	return result, len(hosts) - len(result)
+}
+
+// CertificateSANHosts builds HTTPS hosts for the DNS names of a certificate presented by host
+// Each connects to the IP of host on port 443 and sends the name as Host header / TLS SNI
+// Wildcard names, IP addresses and the names host already uses are skipped
+func CertificateSANHosts(host Host, names []string) []Host {
+	if host.IP == "" {
+		return nil
+	}
+
+	seen := map[string]bool{
+		strings.ToLower(host.BaseAddress): true,
+		strings.ToLower(host.VirtualHost): true,
+	}
+
+	var hosts []Host
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if name == "" || seen[name] || strings.Contains(name, "*") || net.ParseIP(name) != nil {
+			continue
+		}
+		seen[name] = true
+
+		hosts = append(hosts, Host{
+			BaseAddress: name,
+			IP:          host.IP,
+			Port:        443,
+			Protocol:    "https",
+			URL:         formatHostURL("https", host.IP, 443),
+			VirtualHost: name,
+			ASN:         host.ASN,
+		})
+	}
+
+	return hosts
+}
+
+// ApplyVirtualHosts sets the Host header / TLS SNI used when connecting to hosts by IP
+// With useReverseDNS, hosts resolved to a DNS name are connected via their IP while
+// sending the name, which reaches vhost-gated content even when the name does not resolve
+// A non-empty virtualHost is used for all remaining IP-based hosts
+// Returns the number of hosts that received a virtual host
+func ApplyVirtualHosts(hosts []Host, virtualHost string, useReverseDNS bool) int {
+	applied := 0
+	for i := range hosts {
+		host := &hosts[i]
+		if host.IP == "" {
+			continue
+		}
+
+		if host.BaseAddress != host.IP {
+			if !useReverseDNS {
+				continue
+			}
+			// Connect to the IP but present the DNS name
+			host.VirtualHost = host.BaseAddress
+			host.URL = formatHostURL(host.Protocol, host.IP, host.Port)
+			applied++
+			continue
+		}
+
+		if virtualHost != "" {
+			host.VirtualHost = virtualHost
+			applied++
+		}
+	}
+
+	return applied
+}
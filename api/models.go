@@ -2,10 +2,16 @@ package api
 
 // CensysResult represents a result item from Censys API
 type CensysResult struct {
-	IP              string    `json:"ip"`
-	DNS             DNS       `json:"dns"`
-	Services        []Service `json:"services"`
-	MatchedServices []Service `json:"matched_services"`
+	IP               string           `json:"ip"`
+	DNS              DNS              `json:"dns"`
+	AutonomousSystem AutonomousSystem `json:"autonomous_system"`
+	Services         []Service        `json:"services"`
+	MatchedServices  []Service        `json:"matched_services"`
+}
+
+// AutonomousSystem contains the autonomous system a host belongs to
+type AutonomousSystem struct {
+	ASN int `json:"asn"`
 }
 
 // DNS contains DNS information from Censys
@@ -33,6 +39,8 @@ type Host struct {
 	Port        int
 	Protocol    string
 	URL         string
+	VirtualHost string // Host header and TLS SNI to send when connecting by IP (optional)
+	ASN         int    // Autonomous system number (0 if unknown)
 }
 
 // FoundFile represents a file found during crawling
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"censei/logging"
+)
+
+// lowQuotaRatio is the share of remaining requests below which pacing backs off
+const lowQuotaRatio = 0.2
+
+// pagePacer spaces out paginated API requests to stay below rate limits
+// The delay stays between min and max: it doubles when the API signals a low quota
+// or rejects a request with 429, and halves again while the quota is healthy
+type pagePacer struct {
+	min    time.Duration
+	max    time.Duration
+	delay  time.Duration
+	logger *logging.Logger
+}
+
+// newPagePacer creates a pacer from millisecond bounds; max 0 disables pacing
+func newPagePacer(minDelayMS, maxDelayMS int, logger *logging.Logger) *pagePacer {
+	pacer := &pagePacer{
+		min:    time.Duration(minDelayMS) * time.Millisecond,
+		max:    time.Duration(maxDelayMS) * time.Millisecond,
+		logger: logger,
+	}
+	if pacer.max < pacer.min {
+		pacer.max = pacer.min
+	}
+	pacer.delay = pacer.min
+	return pacer
+}
+
+// enabled reports whether any delay may be inserted
+func (p *pagePacer) enabled() bool {
+	return p.max > 0
+}
+
+// wait sleeps for the current delay before the next page request or until ctx is cancelled
+func (p *pagePacer) wait(ctx context.Context) {
+	if p.delay > 0 {
+		p.logger.Debug("Waiting %v before next page", p.delay)
+		sleepContext(ctx, p.delay)
+	}
+}
+
+// sleepContext pauses for delay or until ctx is cancelled
+func sleepContext(ctx context.Context, delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// observe adjusts the delay based on rate limit headers of a successful response
+func (p *pagePacer) observe(headers http.Header) {
+	if !p.enabled() {
+		return
+	}
+
+	if retryAfter, ok := parseRetryAfter(headers); ok {
+		p.setDelay(retryAfter)
+		return
+	}
+
+	remaining, remainingOK := headerInt(headers, "X-RateLimit-Remaining", "RateLimit-Remaining")
+	limit, limitOK := headerInt(headers, "X-RateLimit-Limit", "RateLimit-Limit")
+	if remainingOK && limitOK && limit > 0 && float64(remaining)/float64(limit) < lowQuotaRatio {
+		p.logger.Debug("API rate limit quota low (%d/%d remaining), slowing down", remaining, limit)
+		p.setDelay(p.increased())
+		return
+	}
+
+	// Quota healthy or unknown: speed up again towards the minimum delay
+	p.setDelay(p.delay / 2)
+}
+
+// backoff increases the delay after a rate-limited (429) response
+func (p *pagePacer) backoff(headers http.Header) {
+	if retryAfter, ok := parseRetryAfter(headers); ok && retryAfter > p.increased() {
+		p.setDelay(retryAfter)
+		return
+	}
+	p.setDelay(p.increased())
+}
+
+// increased returns the doubled delay, starting from an eighth of max when no delay is set
+func (p *pagePacer) increased() time.Duration {
+	if p.delay == 0 {
+		return p.max / 8
+	}
+	return p.delay * 2
+}
+
+// setDelay clamps the delay to the configured bounds
+func (p *pagePacer) setDelay(delay time.Duration) {
+	if delay < p.min {
+		delay = p.min
+	}
+	if delay > p.max {
+		delay = p.max
+	}
+	p.delay = delay
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds
+func parseRetryAfter(headers http.Header) (time.Duration, bool) {
+	seconds, ok := headerInt(headers, "Retry-After")
+	if !ok || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// headerInt returns the first of the given headers that holds an integer
+func headerInt(headers http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		if value, err := strconv.Atoi(headers.Get(name)); err == nil {
+			return value, true
+		}
+	}
+	return 0, false
+}
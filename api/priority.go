@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"censei/config"
+)
+
+// HostPrioritizer scores hosts with the host_priority rules so promising hosts are scanned first
+type HostPrioritizer struct {
+	rules    []config.HostPriorityRule
+	patterns []*regexp.Regexp // Compiled rule patterns (nil for rules without a pattern)
+}
+
+// NewHostPrioritizer compiles host_priority rules
+func NewHostPrioritizer(rules []config.HostPriorityRule) (*HostPrioritizer, error) {
+	p := &HostPrioritizer{rules: rules}
+	for i, rule := range rules {
+		var pattern *regexp.Regexp
+		if rule.Pattern != "" {
+			var err error
+			pattern, err = regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid host_priority pattern #%d %q: %w", i+1, rule.Pattern, err)
+			}
+		}
+		p.patterns = append(p.patterns, pattern)
+	}
+	return p, nil
+}
+
+// Score returns the sum of the scores of all rules matching host
+// A rule matches when all of its conditions hold
+func (p *HostPrioritizer) Score(host Host) int {
+	score := 0
+	for i, rule := range p.rules {
+		if pattern := p.patterns[i]; pattern != nil &&
+			!pattern.MatchString(host.URL) && !pattern.MatchString(host.BaseAddress) &&
+			(host.VirtualHost == "" || !pattern.MatchString(host.VirtualHost)) {
+			continue
+		}
+		if len(rule.ASNs) > 0 && !containsInt(rule.ASNs, host.ASN) {
+			continue
+		}
+		if rule.NonstandardPort && isStandardPort(host.Protocol, host.Port) {
+			continue
+		}
+		score += rule.Score
+	}
+	return score
+}
+
+// Sort orders hosts by descending score; hosts with equal scores keep their API order
+// Returns the number of hosts with a non-zero score
+func (p *HostPrioritizer) Sort(hosts []Host) int {
+	if len(p.rules) == 0 {
+		return 0
+	}
+
+	// Scores are kept next to the hosts, as URLs are not unique with virtual hosts
+	type scoredHost struct {
+		host  Host
+		score int
+	}
+	entries := make([]scoredHost, len(hosts))
+	scored := 0
+	for i, host := range hosts {
+		entries[i] = scoredHost{host: host, score: p.Score(host)}
+		if entries[i].score != 0 {
+			scored++
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].score > entries[j].score
+	})
+	for i, entry := range entries {
+		hosts[i] = entry.host
+	}
+	return scored
+}
+
+// isStandardPort checks if port is the default port of protocol
+func isStandardPort(protocol string, port int) bool {
+	switch protocol {
+	case "ftp":
+		return port == 21
+	case "smb":
+		return port == 445
+	}
+	return port == 80 || port == 443
+}
+
+// containsInt checks if values contains value
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateQuery catches obvious syntax errors in a legacy or Platform API v3 query before it
+// is sent, so a malformed query does not spend a round-trip and quota on an API error
+// Checks for an empty query, unbalanced double quotes and unbalanced parentheses; parentheses
+// inside quoted strings and backslash-escaped characters are ignored
+func ValidateQuery(query string) error {
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("query is empty")
+	}
+
+	var openParens []int // Positions of parentheses not closed yet
+	quoteStart := -1     // Position of the open double quote, -1 outside quotes
+	escaped := false
+	for i, r := range query {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			if quoteStart < 0 {
+				quoteStart = i
+			} else {
+				quoteStart = -1
+			}
+		case quoteStart >= 0:
+			// Parentheses in quoted strings are literal text
+		case r == '(':
+			openParens = append(openParens, i)
+		case r == ')':
+			if len(openParens) == 0 {
+				return fmt.Errorf("unbalanced parentheses: ')' at position %d has no matching '('", i+1)
+			}
+			openParens = openParens[:len(openParens)-1]
+		}
+	}
+
+	if quoteStart >= 0 {
+		return fmt.Errorf("unbalanced quotes: '\"' at position %d is never closed", quoteStart+1)
+	}
+	if len(openParens) > 0 {
+		return fmt.Errorf("unbalanced parentheses: '(' at position %d is never closed", openParens[len(openParens)-1]+1)
+	}
+	return nil
+}
@@ -7,11 +7,32 @@ import (
 	"strconv"
 	"strings"
 
+	"censei/api"
 	"censei/config"
 )
 
+// Selection is a query chosen in the interactive menu, with the command line overrides applied
+type Selection struct {
+	Query          string
+	Filters        []string
+	Check          bool
+	TargetFileName string
+}
+
 // ShowMenuWithCheck displays an interactive menu for query selection with file checking options
+// If several queries are selected, only the first one is returned
 func ShowMenuWithCheck(queries []config.Query, customFilterStr string, defaultCheck bool, defaultTargetFile string, isLegacyMode bool) (string, []string, bool, string) {
+	selections := ShowMenuMulti(queries, customFilterStr, defaultCheck, defaultTargetFile, isLegacyMode)
+	if len(selections) == 0 {
+		return "", nil, false, ""
+	}
+	return selections[0].Query, selections[0].Filters, selections[0].Check, selections[0].TargetFileName
+}
+
+// ShowMenuMulti displays the interactive query menu and returns the selected queries in order
+// Besides a single query, a comma-separated list of numbers (e.g. 1,4,7) or "all" selects
+// several predefined queries to run in sequence
+func ShowMenuMulti(queries []config.Query, customFilterStr string, defaultCheck bool, defaultTargetFile string, isLegacyMode bool) []Selection {
 	// Display banner with mode indication
 	PrintBannerWithMode(isLegacyMode)
 
@@ -77,6 +98,9 @@ func ShowMenuWithCheck(queries []config.Query, customFilterStr string, defaultCh
 		} else {
 			fmt.Println("\n[c] Custom query")
 		}
+		if len(queries) > 1 {
+			fmt.Println("Several queries: enter numbers separated by commas (e.g. 1,4,7) or \"all\"")
+		}
 
 		fmt.Println("\n═══════════════════════════════════════════════════════════════")
 		fmt.Print("Enter selection: ")
@@ -98,6 +122,20 @@ func ShowMenuWithCheck(queries []config.Query, customFilterStr string, defaultCh
 			input = strconv.Itoa(len(queries) + 1)
 		}
 
+		// Several predefined queries run in sequence
+		if input == "all" || strings.Contains(input, ",") {
+			numbers, err := parseSelection(input, len(queries))
+			if err != nil {
+				fmt.Printf("Invalid selection: %v. Please try again.\n", err)
+				continue
+			}
+			selections := make([]Selection, 0, len(numbers))
+			for _, num := range numbers {
+				selections = append(selections, predefinedSelection(queries[num-1], customFilterStr, defaultCheck, defaultTargetFile))
+			}
+			return selections
+		}
+
 		// Convert to number
 		num, err := strconv.Atoi(input)
 		if err != nil || num < 1 || num > len(queries)+1 {
@@ -115,6 +153,10 @@ func ShowMenuWithCheck(queries []config.Query, customFilterStr string, defaultCh
 				fmt.Println("Query cannot be empty. Please try again.")
 				continue
 			}
+			if err := api.ValidateQuery(query); err != nil {
+				fmt.Printf("Invalid query: %v. Please try again.\n", err)
+				continue
+			}
 
 			// Parse custom filters if provided
 			var filters []string
@@ -147,31 +189,66 @@ func ShowMenuWithCheck(queries []config.Query, customFilterStr string, defaultCh
 				targetFile = strings.TrimSpace(targetInput)
 			}
 
-			return query, filters, check, targetFile
+			return []Selection{{Query: query, Filters: filters, Check: check, TargetFileName: targetFile}}
 		}
 
 		// Predefined query
-		selectedQuery := queries[num-1]
+		return []Selection{predefinedSelection(queries[num-1], customFilterStr, defaultCheck, defaultTargetFile)}
+	}
+}
 
-		// Use custom filters if provided, otherwise use the predefined ones
-		filters := selectedQuery.Filters
-		if customFilterStr != "" {
-			filters = ParseFilters(customFilterStr)
-		}
+// predefinedSelection applies the command line overrides to a query from the queries file
+func predefinedSelection(selectedQuery config.Query, customFilterStr string, defaultCheck bool, defaultTargetFile string) Selection {
+	// Use custom filters if provided, otherwise use the predefined ones
+	filters := selectedQuery.Filters
+	if customFilterStr != "" {
+		filters = ParseFilters(customFilterStr)
+	}
 
-		// Use query's check settings, or override with command line if provided
-		check := selectedQuery.Check
-		if defaultCheck {
-			check = true
-		}
+	// Use query's check settings, or override with command line if provided
+	check := selectedQuery.Check
+	if defaultCheck {
+		check = true
+	}
+
+	targetFile := selectedQuery.TargetFileName
+	if defaultTargetFile != "" {
+		targetFile = defaultTargetFile
+	}
+
+	return Selection{Query: selectedQuery.Query, Filters: filters, Check: check, TargetFileName: targetFile}
+}
 
-		targetFile := selectedQuery.TargetFileName
-		if defaultTargetFile != "" {
-			targetFile = defaultTargetFile
+// parseSelection parses "all" or a comma-separated list of query numbers (1-based)
+// Duplicates are dropped, the order of the list is kept
+func parseSelection(input string, count int) ([]int, error) {
+	var numbers []int
+	if input == "all" {
+		for num := 1; num <= count; num++ {
+			numbers = append(numbers, num)
 		}
+		return numbers, nil
+	}
 
-		return selectedQuery.Query, filters, check, targetFile
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		num, err := strconv.Atoi(part)
+		if err != nil || num < 1 || num > count {
+			return nil, fmt.Errorf("%q is not a query number between 1 and %d", part, count)
+		}
+		if !seen[num] {
+			seen[num] = true
+			numbers = append(numbers, num)
+		}
+	}
+	if len(numbers) == 0 {
+		return nil, fmt.Errorf("no query numbers given")
 	}
+	return numbers, nil
 }
 
 // ShowMenu provides backward compatibility with the original interface
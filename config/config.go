@@ -3,8 +3,11 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -21,16 +24,176 @@ type Config struct {
 	// General settings
 	OutputDir             string `json:"output_dir"`
 	HTTPTimeoutSeconds    int    `json:"http_timeout_seconds"`
+	CheckTimeoutSeconds   int    `json:"check_timeout_seconds"`
 	MaxConcurrentRequests int    `json:"max_concurrent_requests"`
 	LogLevel              string `json:"log_level"`
 	LogFile               string `json:"log_file"`
+	LogFormat             string `json:"log_format"`
+	LogMaxSizeMB          int    `json:"log_max_size_mb"`
+	LogMaxBackups         int    `json:"log_max_backups"`
 	CheckDir              string `json:"check_dir"`
 	BinaryOutputFile      string `json:"binary_output_file"`
 	MaxLinksPerDirectory  int    `json:"max_links_per_directory"`
 	MaxTotalLinks         int    `json:"max_total_links"`
+	MaxTotalLinksGlobal   int    `json:"max_total_links_global"`
+	StopAtGlobalLinkCap   bool   `json:"stop_at_global_link_cap"`
 	MaxSkipsBeforeBlock   int    `json:"max_skips_before_block"`
 	BlocklistFile         string `json:"blocklist_file"`
 	EnableBlocklist       bool   `json:"enable_blocklist"`
+	BlocklistWebhookURL   string `json:"blocklist_webhook_url"`
+	BlocklistBackend      string `json:"blocklist_backend"`
+	BlocklistRedisURL     string `json:"blocklist_redis_url"`
+	ProxyURL              string `json:"proxy_url"`
+	OutputMode            string `json:"output_mode"`
+	BinariesOnly          bool   `json:"binaries_only"`
+	CategoryOutput        bool   `json:"category_output"`
+	PathOnlyOutput        bool   `json:"path_only_output"`
+	PathOnlyIncludeHost   bool   `json:"path_only_include_host"`
+	ReportMismatches      bool   `json:"report_mismatches"`
+	RespectRobots         bool   `json:"respect_robots"`
+	SkipHostsFile         string `json:"skip_hosts_file"`
+	AlsoScanIP            bool   `json:"also_scan_ip"`
+	VirtualHost           string `json:"virtual_host"`
+	ReverseDNSVirtualHost bool   `json:"reverse_dns_virtual_host"`
+	MaxListingChunks      int    `json:"max_listing_chunks"`
+	ExportDirectories     bool   `json:"export_directories"`
+	ExportExtensions      bool   `json:"export_extensions"`
+	VerboseHostOutput     bool   `json:"verbose_host_output"`
+	RequireHTML           bool   `json:"require_html"`
+	MinListingBytes       int    `json:"min_listing_bytes"`
+	AcceptLanguage        string `json:"accept_language"`
+	ListArchiveContents   bool   `json:"list_archive_contents"`
+	RunIDInFilenames      bool   `json:"run_id_in_filenames"`
+	EnableFTP             bool   `json:"enable_ftp"`
+	EnableSMB             bool   `json:"enable_smb"`
+	LogDetectionReason    bool   `json:"log_detection_reason"`
+	OutputFormat          string `json:"output_format"`
+	VerifyListingServer   bool   `json:"verify_listing_server"`
+	VerifySignatures      bool   `json:"verify_signatures"`
+	SignatureBytes        int    `json:"signature_bytes"`
+	MaxChecks             int    `json:"max_checks"`
+	MaxBinariesPerHost    int    `json:"max_binaries_per_host"`
+	MaxBreadthDepth       int    `json:"max_breadth_depth"`
+	JSONOutput            bool   `json:"json_output"`
+
+	// Subpaths probed for listings on every online host besides the root (e.g. "files", "uploads")
+	RootSubpaths []string `json:"root_subpaths"`
+
+	// Response headers recorded for every online host in headers.jsonl (e.g. "Server", "X-*")
+	CaptureHeaders []string `json:"capture_headers"`
+
+	// Record TLS certificate details of HTTPS hosts in certificates.jsonl
+	CaptureTLSInfo bool `json:"capture_tls_info"`
+
+	// File category overrides: category -> extensions (e.g. {"executable": [".ps1"]})
+	FileCategories map[string][]string `json:"file_categories"`
+
+	// Query parameters removed from found links before dedup and filtering ("all" = whole query)
+	StripQueryParams []string `json:"strip_query_params"`
+
+	// Custom directory listing indicators, added to or replacing the built-in ones
+	DirectoryIndicators        []string `json:"directory_indicators"`
+	ReplaceDirectoryIndicators bool     `json:"replace_directory_indicators"`
+	ListingLinkThreshold       int      `json:"listing_link_threshold"`
+
+	// <a> tags processed per page, guards against hostile pages with millions of links (0 = 100000)
+	MaxAnchorsPerPage int `json:"max_anchors_per_page"`
+
+	// Regex find/replace rules applied to found-file URLs before dedup and filtering
+	URLRewrites []URLRewrite `json:"url_rewrites"`
+
+	// Extensions that are content-verified in check mode (empty = all filtered files)
+	CheckExtensions []string `json:"check_extensions"`
+
+	// Schemes of found links that are kept (empty = http and https)
+	AllowedLinkSchemes []string `json:"allowed_link_schemes"`
+
+	// Redirect handling (max_redirects of 0 = 5 hops)
+	FollowRedirects         bool `json:"follow_redirects"`
+	MaxRedirects            int  `json:"max_redirects"`
+	AllowCrossHostRedirects bool `json:"allow_cross_host_redirects"`
+
+	// Retry hosts failing to connect once with the other scheme (http <-> https)
+	RetryAlternateScheme bool `json:"retry_alternate_scheme"`
+
+	// Target rate limiting (0 retries = hosts answering 429/503 are treated as offline)
+	ThrottleRetries      int `json:"throttle_retries"`
+	ThrottlePauseSeconds int `json:"throttle_pause_seconds"`
+
+	// Saving of confirmed binaries (max_download_size in bytes, 0 = 100 MB)
+	DownloadBinaries bool   `json:"download_binaries"`
+	DownloadsDir     string `json:"downloads_dir"`
+	MaxDownloadSize  int64  `json:"max_download_size"`
+
+	// Size range of filtered files in bytes, read with a HEAD request (0 = no limit)
+	MinFileSize     int64 `json:"min_file_size"`
+	MaxFileSize     int64 `json:"max_file_size"`
+	DropUnknownSize bool  `json:"drop_unknown_size"`
+
+	// Prefix entropy in bits per byte flagging packed/encrypted binaries (0 = disabled)
+	EntropyThreshold float64 `json:"entropy_threshold"`
+
+	// Requests per second sent to a single base host (0 = unlimited)
+	RequestsPerSecondPerHost float64 `json:"requests_per_second_per_host"`
+
+	// Host header port handling (nil keeps the default: port included)
+	HostHeaderIncludePort *bool `json:"host_header_include_port"`
+
+	// Crawl DNS names from certificate SANs as extra vhosts on the same IP (0 = 10 per host)
+	FollowCertSANs     bool `json:"follow_cert_sans"`
+	MaxCertSANsPerHost int  `json:"max_cert_sans_per_host"`
+
+	// Address families to scan (nil keeps the default: enabled)
+	ScanIPv4 *bool `json:"scan_ipv4"`
+	ScanIPv6 *bool `json:"scan_ipv6"`
+
+	// Never scan hosts with private, loopback or link-local addresses
+	SkipPrivateIPs bool `json:"skip_private_ips"`
+
+	// Only scan hosts whose name matches this regular expression (empty = all hosts)
+	HostNameRegex string `json:"host_name_regex"`
+
+	// Ports of hosts to scan (empty allowlist = all ports) and to skip
+	AllowedPorts []int `json:"allowed_ports"`
+	BlockedPorts []int `json:"blocked_ports"`
+
+	// Webhook receiving confirmed binaries in batches (interval 0 = one summary POST at scan end)
+	WebhookURL             string `json:"webhook_url"`
+	WebhookIntervalSeconds int    `json:"webhook_interval_seconds"`
+
+	// Scan summary posted to a Slack or Discord incoming webhook (empty format = detect from URL)
+	NotifyOnComplete bool   `json:"notify_on_complete"`
+	NotifyWebhookURL string `json:"notify_webhook_url"`
+	NotifyFormat     string `json:"notify_format"`
+
+	// Command run after the scan with the statistics in CENSEI_* environment variables
+	// (timeout 0 = 300 seconds); a failing command only sets the exit code with fail_on_hook_error
+	PostScanCommand        []string `json:"post_scan_command"`
+	PostScanTimeoutSeconds int      `json:"post_scan_timeout_seconds"`
+	FailOnHookError        bool     `json:"fail_on_hook_error"`
+
+	// Skip hosts whose root listing has the same file names as a host already scanned
+	SkipMirrorHosts bool `json:"skip_mirror_hosts"`
+
+	// Wall-clock interval of heartbeat log lines with the current statistics (0 = disabled)
+	HeartbeatIntervalSeconds int `json:"heartbeat_interval_seconds"`
+
+	// Address of the Prometheus metrics endpoint, e.g. "127.0.0.1:9090" (empty = disabled)
+	MetricsAddr string `json:"metrics_addr"`
+
+	// Scoring rules ordering hosts so promising ones are scanned first (empty = API order)
+	HostPriority []HostPriorityRule `json:"host_priority"`
+
+	// User-Agent settings (shared by crawler and file checker)
+	UserAgent        string   `json:"user_agent"`
+	UserAgentPool    []string `json:"user_agent_pool"`
+	UserAgentPerHost bool     `json:"user_agent_per_host"`
+
+	// HTTP transport settings (pool sizes of 0 keep the defaults: 200 total, 20 per host)
+	MaxIdleConns            int  `json:"max_idle_conns"`
+	MaxIdleConnsPerHost     int  `json:"max_idle_conns_per_host"`
+	EnableHTTP2             bool `json:"enable_http2"`
+	IdleReapIntervalSeconds int  `json:"idle_reap_interval_seconds"`
 
 	// Legacy CLI parameters (for censys-cli tool)
 	LegacyPages        int    `json:"legacy_pages"`
@@ -39,19 +202,68 @@ type Config struct {
 	LegacySortOrder    string `json:"legacy_sort_order"`
 	LegacyVirtualHosts string `json:"legacy_virtual_hosts"`
 
+	// Extra censys search flags added before the query, e.g. ["--fields", "ip,services.port"]
+	LegacyExtraArgs []string `json:"legacy_extra_args"`
+
 	// Platform API v3 parameters
-	V3MaxResults int `json:"v3_max_results"`
+	V3MaxResults     int  `json:"v3_max_results"`
+	V3PageSize       int  `json:"v3_page_size"`
+	PipelineCrawl    bool `json:"pipeline_crawl"`
+	V3MinPageDelayMS int  `json:"v3_min_page_delay_ms"`
+	V3MaxPageDelayMS int  `json:"v3_max_page_delay_ms"`
+	SaveRawPages     bool `json:"save_raw_pages"`
+
+	// Platform API v3 retries for transient errors (0 retries = fail immediately)
+	V3MaxRetries       int `json:"v3_max_retries"`
+	V3RetryBaseDelayMS int `json:"v3_retry_base_delay_ms"`
+
+	// Keep censys_results.json after the hosts were extracted (nil keeps the default: kept)
+	KeepRawResults *bool `json:"keep_raw_results"`
+
+	// Directory fetches of recursive scans in flight across all hosts (0 = unlimited)
+	MaxConcurrentDirFetches int `json:"max_concurrent_dir_fetches"`
+
+	// Write scan_report.json with blocked/skipped hosts and link totals per base host
+	WriteScanReport bool `json:"write_scan_report"`
 
 	// Query file paths
 	QueriesFileV3     string `json:"queries_file_v3"`
 	QueriesFileLegacy string `json:"queries_file_legacy"`
 }
 
+// validateLegacyArg rejects legacy_extra_args entries that could alter the censys command
+// beyond adding a flag: shell metacharacters, control characters and the flags Censei sets itself
+func validateLegacyArg(arg string) error {
+	if strings.ContainsAny(arg, ";|&$`<>\\\"'\n\r\x00") {
+		return fmt.Errorf("legacy_extra_args entry %q contains characters that are not allowed", arg)
+	}
+	switch flag, _, _ := strings.Cut(arg, "="); flag {
+	case "--api-id", "--api-secret", "--output", "-o":
+		return fmt.Errorf("legacy_extra_args cannot set %s, it is set by Censei", flag)
+	}
+	return nil
+}
+
+// URLRewrite is a regex find/replace rule for found-file URLs
+type URLRewrite struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+// HostPriorityRule adds Score to every host matching all of its set conditions
+type HostPriorityRule struct {
+	Pattern         string `json:"pattern"`          // Regex matched against host URL and name
+	ASNs            []int  `json:"asns"`             // Autonomous system numbers
+	NonstandardPort bool   `json:"nonstandard_port"` // Port other than the protocol default
+	Score           int    `json:"score"`
+}
+
 // Query represents a predefined Censys query with its filters
 type Query struct {
 	Name           string   `json:"name"`
 	Query          string   `json:"query"`
 	Filters        []string `json:"filters"`
+	FilterRegex    []string `json:"filter_regex"`
 	Check          bool     `json:"check"`
 	TargetFileName string   `json:"target_filename"`
 	Recursive      string   `json:"recursive"`
@@ -118,27 +330,103 @@ func ValidateForV3(cfg *Config) error {
 	if cfg.V3MaxResults <= 0 {
 		return fmt.Errorf("v3_max_results must be greater than 0")
 	}
+	if cfg.V3MaxRetries < 0 || cfg.V3RetryBaseDelayMS < 0 {
+		return fmt.Errorf("v3_max_retries and v3_retry_base_delay_ms cannot be negative")
+	}
+	// V3PageSize of 0 (missing) falls back to the maximum of 100
+	if cfg.V3PageSize < 0 || cfg.V3PageSize > 100 {
+		return fmt.Errorf("v3_page_size must be between 1 and 100")
+	}
 	// OrganizationID is optional, no validation needed
 	return nil
 }
 
 // LoadQueries loads predefined queries from a file
 func LoadQueries(path string) ([]Query, error) {
+	queries, err := readQueries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail early on filter patterns that do not compile
+	for _, query := range queries {
+		for _, pattern := range query.FilterRegex {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("query %q: filter_regex pattern %q is not a valid regular expression: %w", query.Name, pattern, err)
+			}
+		}
+	}
+
+	// Log successful queries load (before logger is initialized)
+	fmt.Printf("[INFO] Loaded %d queries from %s\n", len(queries), path)
+
+	return queries, nil
+}
+
+// readQueries reads and parses a queries file without validating the entries
+func readQueries(path string) ([]Query, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read queries file: %w", err)
 	}
 
 	var queries []Query
-	err = json.Unmarshal(data, &queries)
-	if err != nil {
+	if err := json.Unmarshal(data, &queries); err != nil {
 		return nil, fmt.Errorf("failed to parse queries file: %w", err)
 	}
+	return queries, nil
+}
 
-	// Log successful queries load (before logger is initialized)
-	fmt.Printf("[INFO] Loaded %d queries from %s\n", len(queries), path)
+// QueryValidation is the result of validating one entry of a queries file
+type QueryValidation struct {
+	Query    Query
+	Problems []string // Empty if the entry is valid
+}
 
-	return queries, nil
+// ValidateQueriesFile parses a queries file and validates every entry, see ValidateQuery
+// Only a file that cannot be read or parsed returns an error
+func ValidateQueriesFile(path string) ([]QueryValidation, error) {
+	queries, err := readQueries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]QueryValidation, 0, len(queries))
+	for _, query := range queries {
+		results = append(results, QueryValidation{Query: query, Problems: ValidateQuery(query)})
+	}
+	return results, nil
+}
+
+// ValidateQuery checks a query entry for mistakes that would make it fail or be ignored
+func ValidateQuery(query Query) []string {
+	var problems []string
+
+	if strings.TrimSpace(query.Query) == "" {
+		problems = append(problems, "query is empty")
+	}
+	if query.Recursive != "" && query.Recursive != "yes" && query.Recursive != "no" {
+		problems = append(problems, fmt.Sprintf("recursive must be \"yes\" or \"no\", got %q", query.Recursive))
+	}
+	if query.MaxDepth < 0 {
+		problems = append(problems, "max-depth cannot be negative")
+	} else if query.Recursive == "yes" && query.MaxDepth < 1 {
+		problems = append(problems, "max-depth must be at least 1 for recursive scanning")
+	}
+	if query.TargetFileName != "" && !query.Check {
+		problems = append(problems, "target_filename is set but check is disabled")
+	}
+	// Without a target only filtered files are checked
+	if query.Check && query.TargetFileName == "" && len(query.Filters) == 0 && len(query.FilterRegex) == 0 {
+		problems = append(problems, "check is enabled without target_filename or filters, so no files would be checked")
+	}
+	for _, pattern := range query.FilterRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			problems = append(problems, fmt.Sprintf("filter_regex pattern %q is not a valid regular expression: %v", pattern, err))
+		}
+	}
+
+	return problems
 }
 
 // validateConfig ensures that required fields are present
@@ -147,10 +435,169 @@ func validateConfig(cfg *Config) error {
 	if cfg.HTTPTimeoutSeconds <= 0 {
 		return fmt.Errorf("http_timeout_seconds must be greater than 0")
 	}
+	if cfg.CheckTimeoutSeconds < 0 {
+		return fmt.Errorf("check_timeout_seconds cannot be negative")
+	}
 	if cfg.MaxConcurrentRequests <= 0 {
 		return fmt.Errorf("max_concurrent_requests must be greater than 0")
 	}
 
+	if cfg.MaxIdleConns < 0 || cfg.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("max_idle_conns and max_idle_conns_per_host cannot be negative")
+	}
+	if cfg.ThrottleRetries < 0 || cfg.ThrottlePauseSeconds < 0 {
+		return fmt.Errorf("throttle_retries and throttle_pause_seconds cannot be negative")
+	}
+	if cfg.MaxDownloadSize < 0 {
+		return fmt.Errorf("max_download_size cannot be negative")
+	}
+	if cfg.EntropyThreshold < 0 || cfg.EntropyThreshold > 8 {
+		return fmt.Errorf("entropy_threshold must be between 0 and 8")
+	}
+	if cfg.RequestsPerSecondPerHost < 0 {
+		return fmt.Errorf("requests_per_second_per_host cannot be negative")
+	}
+	if cfg.ScanIPv4 != nil && !*cfg.ScanIPv4 && cfg.ScanIPv6 != nil && !*cfg.ScanIPv6 {
+		return fmt.Errorf("scan_ipv4 and scan_ipv6 cannot both be false")
+	}
+	for _, port := range append(append([]int{}, cfg.AllowedPorts...), cfg.BlockedPorts...) {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("allowed_ports and blocked_ports must be between 1 and 65535, got %d", port)
+		}
+	}
+	if cfg.MinFileSize < 0 || cfg.MaxFileSize < 0 {
+		return fmt.Errorf("min_file_size and max_file_size cannot be negative")
+	}
+	if cfg.MaxFileSize > 0 && cfg.MinFileSize > cfg.MaxFileSize {
+		return fmt.Errorf("min_file_size cannot be greater than max_file_size")
+	}
+	for _, arg := range cfg.LegacyExtraArgs {
+		if err := validateLegacyArg(arg); err != nil {
+			return err
+		}
+	}
+	if cfg.MaxBinariesPerHost < 0 {
+		return fmt.Errorf("max_binaries_per_host cannot be negative")
+	}
+	if cfg.MaxTotalLinksGlobal < 0 {
+		return fmt.Errorf("max_total_links_global cannot be negative")
+	}
+	if cfg.ListingLinkThreshold < 0 {
+		return fmt.Errorf("listing_link_threshold cannot be negative")
+	}
+	if cfg.MaxAnchorsPerPage < 0 {
+		return fmt.Errorf("max_anchors_per_page cannot be negative")
+	}
+	if cfg.ReplaceDirectoryIndicators && len(cfg.DirectoryIndicators) == 0 {
+		return fmt.Errorf("replace_directory_indicators requires directory_indicators")
+	}
+	if cfg.MaxCertSANsPerHost < 0 {
+		return fmt.Errorf("max_cert_sans_per_host cannot be negative")
+	}
+	if cfg.MaxRedirects < 0 {
+		return fmt.Errorf("max_redirects cannot be negative")
+	}
+	if cfg.MaxBreadthDepth < 0 {
+		return fmt.Errorf("max_breadth_depth cannot be negative")
+	}
+	if cfg.MaxConcurrentDirFetches < 0 {
+		return fmt.Errorf("max_concurrent_dir_fetches cannot be negative")
+	}
+	if cfg.IdleReapIntervalSeconds < 0 {
+		return fmt.Errorf("idle_reap_interval_seconds cannot be negative")
+	}
+
+	if _, err := regexp.Compile(cfg.HostNameRegex); err != nil {
+		return fmt.Errorf("host_name_regex is not a valid regular expression: %w", err)
+	}
+	for i, rule := range cfg.HostPriority {
+		if rule.Pattern == "" && len(rule.ASNs) == 0 && !rule.NonstandardPort {
+			return fmt.Errorf("host_priority rule #%d needs a pattern, asns or nonstandard_port", i+1)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("host_priority pattern #%d is not a valid regular expression: %w", i+1, err)
+		}
+	}
+	for i, rule := range cfg.URLRewrites {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("url_rewrites pattern #%d is not a valid regular expression: %w", i+1, err)
+		}
+	}
+
+	switch cfg.BlocklistBackend {
+	case "", "file":
+	case "redis":
+		if cfg.BlocklistRedisURL == "" {
+			return fmt.Errorf("blocklist_redis_url is required for blocklist_backend redis")
+		}
+	default:
+		return fmt.Errorf("blocklist_backend must be \"file\" or \"redis\"")
+	}
+
+	if cfg.WebhookIntervalSeconds < 0 {
+		return fmt.Errorf("webhook_interval_seconds cannot be negative")
+	}
+	if cfg.WebhookURL != "" && !isWebhookURL(cfg.WebhookURL) {
+		return fmt.Errorf("webhook_url is not a valid http(s) URL: %q", cfg.WebhookURL)
+	}
+	if cfg.BlocklistWebhookURL != "" && !isWebhookURL(cfg.BlocklistWebhookURL) {
+		return fmt.Errorf("blocklist_webhook_url is not a valid http(s) URL: %q", cfg.BlocklistWebhookURL)
+	}
+
+	if cfg.NotifyOnComplete {
+		if cfg.NotifyWebhookURL == "" {
+			return fmt.Errorf("notify_on_complete requires notify_webhook_url")
+		}
+		if !isWebhookURL(cfg.NotifyWebhookURL) {
+			return fmt.Errorf("notify_webhook_url is not a valid http(s) URL: %q", cfg.NotifyWebhookURL)
+		}
+		// An empty format is detected from the URL when the notification is sent
+		switch cfg.NotifyFormat {
+		case "", "slack", "discord":
+		default:
+			return fmt.Errorf("notify_format must be \"slack\" or \"discord\"")
+		}
+	}
+
+	if len(cfg.PostScanCommand) > 0 && cfg.PostScanCommand[0] == "" {
+		return fmt.Errorf("post_scan_command must start with the program to run")
+	}
+	if cfg.PostScanTimeoutSeconds < 0 {
+		return fmt.Errorf("post_scan_timeout_seconds cannot be negative")
+	}
+
+	if cfg.LogMaxSizeMB < 0 || cfg.LogMaxBackups < 0 {
+		return fmt.Errorf("log_max_size_mb and log_max_backups cannot be negative")
+	}
+	if cfg.HeartbeatIntervalSeconds < 0 {
+		return fmt.Errorf("heartbeat_interval_seconds cannot be negative")
+	}
+	if cfg.LogFormat != "" && cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		return fmt.Errorf("log_format must be \"text\" or \"json\"")
+	}
+
+	if cfg.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(cfg.MetricsAddr); err != nil {
+			return fmt.Errorf("metrics_addr must be host:port, e.g. \"127.0.0.1:9090\": %w", err)
+		}
+	}
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("proxy_url is not a valid URL: %q", cfg.ProxyURL)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("proxy_url scheme must be http, https or socks5")
+		}
+		// FTP and SMB need raw TCP connections, which HTTP proxies don't carry
+		if (cfg.EnableFTP || cfg.EnableSMB) && !strings.HasPrefix(proxyURL.Scheme, "socks5") {
+			return fmt.Errorf("proxy_url must be a socks5 proxy when enable_ftp or enable_smb is set")
+		}
+	}
+
 	// Validate output directory path to prevent path traversal
 	if cfg.OutputDir == "" {
 		return fmt.Errorf("output_dir path in config cannot be empty")
@@ -175,6 +622,12 @@ func validateConfig(cfg *Config) error {
 		}
 	}
 
+	switch cfg.OutputMode {
+	case "", "overwrite", "timestamped", "prefixed":
+	default:
+		return fmt.Errorf("output_mode must be \"overwrite\", \"timestamped\" or \"prefixed\"")
+	}
+
 	// Validate binary output file path is set
 	if cfg.BinaryOutputFile == "" {
 		return fmt.Errorf("binary_output_file cannot be empty")
@@ -182,3 +635,9 @@ func validateConfig(cfg *Config) error {
 
 	return nil
 }
+
+// isWebhookURL reports whether rawURL is an absolute http(s) URL with a host
+func isWebhookURL(rawURL string) bool {
+	webhookURL, err := url.Parse(rawURL)
+	return err == nil && (webhookURL.Scheme == "http" || webhookURL.Scheme == "https") && webhookURL.Host != ""
+}
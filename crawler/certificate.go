@@ -0,0 +1,96 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"censei/api"
+	"censei/output"
+)
+
+// defaultMaxCertSANsPerHost caps the certificate names followed per host with follow_cert_sans
+const defaultMaxCertSANsPerHost = 10
+
+// certificateRecord describes the leaf certificate of an HTTPS response for certificates.jsonl
+// Returns nil if the connection did not present a certificate
+func certificateRecord(hostURL string, state *tls.ConnectionState) *output.CertificateRecord {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := state.PeerCertificates[0]
+
+	// Subject Alternative Names of all types
+	var sans []string
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	// Self-signed: issued by its own subject and signed by its own key
+	selfSigned := string(cert.RawSubject) == string(cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+
+	fingerprint := sha256.Sum256(cert.Raw)
+	return &output.CertificateRecord{
+		URL:         hostURL,
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		SANs:        sans,
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		Expired:     time.Now().After(cert.NotAfter),
+		SelfSigned:  selfSigned,
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+	}
+}
+
+// registerHostname records the name a host is scanned under, see certificateSANHosts
+func (w *Worker) registerHostname(host api.Host) {
+	if !w.config.FollowCertSANs {
+		return
+	}
+	name := host.VirtualHost
+	if name == "" {
+		name = host.BaseAddress
+	}
+	w.knownHostnames.Store(strings.ToLower(name), true)
+}
+
+// certificateSANHosts returns hosts for the certificate DNS names that are not yet in the scan
+// At most max_cert_sans_per_host names are followed per host
+func (w *Worker) certificateSANHosts(host api.Host, state *tls.ConnectionState) []api.Host {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	maxSANs := w.config.MaxCertSANsPerHost
+	if maxSANs <= 0 {
+		maxSANs = defaultMaxCertSANsPerHost
+	}
+
+	var hosts []api.Host
+	for _, sanHost := range api.CertificateSANHosts(host, state.PeerCertificates[0].DNSNames) {
+		if len(hosts) >= maxSANs {
+			w.logger.Debug("Reached max_cert_sans_per_host (%d) for %s", maxSANs, host.URL)
+			break
+		}
+		if _, known := w.knownHostnames.LoadOrStore(sanHost.VirtualHost, true); known {
+			continue
+		}
+		hosts = append(hosts, sanHost)
+	}
+
+	if len(hosts) > 0 {
+		atomic.AddInt64(&w.certSANHosts, int64(len(hosts)))
+		atomic.AddInt64(&w.stats.totalHosts, int64(len(hosts)))
+		w.logger.Info("Queued %d vhosts from the certificate of %s", len(hosts), host.URL)
+	}
+	return hosts
+}
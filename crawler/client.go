@@ -1,21 +1,64 @@
 package crawler
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"censei/api"
 	"censei/logging"
+	"censei/netproxy"
+	"censei/useragent"
 )
 
+// serverNameKey is the context key carrying the TLS SNI for a request
+type serverNameKey struct{}
+
+// defaultMaxRedirects is the hop limit when following redirects without a configured limit
+const defaultMaxRedirects = 5
+
+// defaultAcceptLanguage requests English listings where servers negotiate by language
+const defaultAcceptLanguage = "en-US,en;q=0.9"
+
+// maxBodySize limits how much of a response body is read per request
+// Limit to 50 MB to handle large directory listings with thousands of files
+// Typical directory listings: 1-100 KB, large ones: 5-20 MB, extreme cases: up to 50 MB
+const maxBodySize = 50 << 20 // 50 MB
+
 // Client handles HTTP requests for crawling
 type Client struct {
-	httpClient *http.Client
-	logger     *logging.Logger
+	httpClient    *http.Client
+	logger        *logging.Logger
+	maxChunks     int       // Additional ranged fetches for truncated listings (0 = disabled)
+	truncatedURLs *sync.Map // URLs whose listing exceeded the body limit
+	userAgents    *useragent.Picker
+	stripHostPort bool // Send the Host header without the port
+	acceptLang    string
+	dialer        *net.Dialer
+	proxyDialer   netproxy.ContextDialer // Tunnel through proxy_url (nil = direct), see SetProxy
+
+	// Response headers recorded per host, see SetCaptureHeaders
+	captureHeaders []string
+
+	// Pausing of hosts that rate limit us, see SetThrottle
+	throttle        *hostThrottle
+	throttleRetries int
+
+	// Per-host request rate shared with the Worker (nil = unlimited)
+	rateLimiter *hostRateLimiter
+
+	// Transports per TLS SNI so keep-alive connections dialed for one
+	// virtual host are never reused for another on the same IP:port
+	sniTransports sync.Map // server name -> *http.Transport
 }
 
 // NewClient creates a new crawler client with optimized connection pooling
@@ -41,6 +84,12 @@ func NewClient(timeoutSeconds int, logger *logging.Logger) *Client {
 		MaxResponseHeaderBytes: 10 << 20,         // 10 MB max header size (prevent abuse)
 	}
 
+	// Custom TLS dialing so the SNI can differ from the dialed IP (virtual hosts)
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(timeoutSeconds) * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
 	client := &http.Client{
 		// Use timeout from config (http_timeout_seconds)
 		// Note: This applies to entire request including body read
@@ -53,57 +102,531 @@ func NewClient(timeoutSeconds int, logger *logging.Logger) *Client {
 		},
 	}
 
-	return &Client{
-		httpClient: client,
-		logger:     logger,
+	c := &Client{
+		httpClient:    client,
+		logger:        logger,
+		truncatedURLs: &sync.Map{},
+		acceptLang:    defaultAcceptLanguage,
+		dialer:        dialer,
 	}
+	transport.DialContext = c.dialContext
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialTLS(ctx, c.dialContext, network, addr, nil)
+	}
+
+	return c
 }
 
-// CheckHostAndFetch combines checking if host is online and fetching its content
-// Returns if the host is online, the HTML content (if any), and any error
-func (c *Client) CheckHostAndFetch(host api.Host) (bool, string, error) {
-	c.logger.Debug("Checking host and fetching content: %s", host.URL)
+// SetRedirects configures whether redirects are followed and how many hops are allowed
+// Redirects to other hosts are only followed if allowCrossHost is set; loops are never followed
+// A redirect that is not followed returns the redirect response itself
+func (c *Client) SetRedirects(follow bool, maxRedirects int, allowCrossHost bool) {
+	if !follow {
+		return
+	}
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.httpClient.Timeout)
-	defer cancel()
+	c.httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			c.logger.Debug("Not following redirect to %s: more than %d redirects", req.URL, maxRedirects)
+			return http.ErrUseLastResponse
+		}
+
+		for _, previous := range via {
+			if previous.URL.String() == req.URL.String() {
+				c.logger.Debug("Not following redirect to %s: redirect loop", req.URL)
+				return http.ErrUseLastResponse
+			}
+		}
+
+		if !allowCrossHost && !sameRedirectHost(via[0], req.URL) {
+			c.logger.Debug("Not following redirect from %s to other host %s", via[0].URL, req.URL)
+			return http.ErrUseLastResponse
+		}
 
+		return nil
+	}
+}
+
+// sameRedirectHost checks if a redirect target stays on the host of the original request
+// The virtual host sent in the Host header counts as the same host
+func sameRedirectHost(original *http.Request, target *url.URL) bool {
+	targetHost := target.Hostname()
+	if strings.EqualFold(targetHost, original.URL.Hostname()) {
+		return true
+	}
+
+	virtualHost := original.Host
+	if h, _, err := net.SplitHostPort(virtualHost); err == nil {
+		virtualHost = h
+	}
+	return virtualHost != "" && strings.EqualFold(targetHost, virtualHost)
+}
+
+// SetThrottle enables pausing hosts that answer 429 or 503 instead of treating them as offline
+// Requests to the base host wait for the Retry-After delay (or defaultPauseSeconds, 0 = 30s)
+// and are retried up to retries times; 0 retries disables throttling
+func (c *Client) SetThrottle(retries int, defaultPauseSeconds int) {
+	if retries <= 0 {
+		return
+	}
+	c.throttle = newHostThrottle(time.Duration(defaultPauseSeconds) * time.Second)
+	c.throttleRetries = retries
+}
+
+// SetCaptureHeaders sets the response headers recorded in FetchResult.Headers
+// A name ending in * matches every header with that prefix (e.g. "X-*")
+func (c *Client) SetCaptureHeaders(names []string) {
+	c.captureHeaders = names
+}
+
+// captureHeaders returns the values of the requested headers present in a response
+// Multiple values of one header are joined with ", "
+func captureHeaders(header http.Header, names []string) map[string]string {
+	captured := make(map[string]string)
+	for _, name := range names {
+		if prefix, ok := strings.CutSuffix(name, "*"); ok {
+			prefix = strings.ToLower(prefix)
+			for key, values := range header {
+				if strings.HasPrefix(strings.ToLower(key), prefix) {
+					captured[key] = strings.Join(values, ", ")
+				}
+			}
+			continue
+		}
+
+		if values := header.Values(name); len(values) > 0 {
+			captured[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+		}
+	}
+	return captured
+}
+
+// SetAcceptLanguage overrides the Accept-Language header sent with crawl requests
+// An empty value keeps the default (en-US,en;q=0.9)
+func (c *Client) SetAcceptLanguage(acceptLanguage string) {
+	if acceptLanguage == "" {
+		acceptLanguage = defaultAcceptLanguage
+	}
+	c.acceptLang = acceptLanguage
+}
+
+// SetConnectionPool overrides the idle connection limits of the transport
+// Values of 0 keep the defaults (200 total, 20 per host)
+func (c *Client) SetConnectionPool(maxIdleConns, maxIdleConnsPerHost int) {
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+	if maxIdleConns > 0 {
+		transport.MaxIdleConns = maxIdleConns
+	}
+	if maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	}
+}
+
+// SetProxy routes all requests through an HTTP, HTTPS or SOCKS5 proxy
+// Connections are tunnelled at the dial level, so TLS (SNI, ALPN) is still negotiated with the host
+// An empty proxyURL keeps direct connections
+func (c *Client) SetProxy(proxyURL string) error {
+	if proxyURL == "" {
+		return nil
+	}
+
+	dialer, err := netproxy.New(proxyURL, c.dialer)
+	if err != nil {
+		return err
+	}
+	c.proxyDialer = dialer
+	return nil
+}
+
+// SetHTTP2 enables HTTP/2 negotiation via ALPN for HTTPS hosts
+// Disabled by default as HTTP/1.1 is faster for many small requests
+func (c *Client) SetHTTP2(enabled bool) {
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok || !enabled {
+		return
+	}
+
+	transport.ForceAttemptHTTP2 = true
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialTLS(ctx, c.dialContext, network, addr, []string{"h2", "http/1.1"})
+	}
+}
+
+// CloseIdleConnections closes keep-alive connections that are currently idle
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+	c.sniTransports.Range(func(_, value interface{}) bool {
+		value.(*http.Transport).CloseIdleConnections()
+		return true
+	})
+}
+
+// do sends a request, using a dedicated transport when it carries a TLS SNI
+// The default transport pools connections by scheme and IP:port only
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	serverName, _ := req.Context().Value(serverNameKey{}).(string)
+	base, ok := c.httpClient.Transport.(*http.Transport)
+	if serverName == "" || !ok {
+		return c.httpClient.Do(req)
+	}
+
+	transport, ok := c.sniTransports.Load(serverName)
+	if !ok {
+		transport, _ = c.sniTransports.LoadOrStore(serverName, base.Clone())
+	}
+
+	client := *c.httpClient
+	client.Transport = transport.(*http.Transport)
+	return client.Do(req)
+}
+
+// SetMaxListingChunks enables ranged fetches to continue reading truncated listings
+// Each chunk is up to 50 MB; 0 disables continuation
+func (c *Client) SetMaxListingChunks(maxChunks int) {
+	c.maxChunks = maxChunks
+}
+
+// SetUserAgents configures how the User-Agent is chosen for each request
+func (c *Client) SetUserAgents(picker *useragent.Picker) {
+	c.userAgents = picker
+}
+
+// SetStripHostPort controls whether the port is removed from the Host header
+// Some servers behind proxies expect the header without a nonstandard port
+func (c *Client) SetStripHostPort(strip bool) {
+	c.stripHostPort = strip
+}
+
+// newRequest creates a GET request for a host with the crawler headers applied
+func (c *Client) newRequest(ctx context.Context, host api.Host) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", host.URL, nil)
 	if err != nil {
-		c.logger.Error("Failed to create HTTP request for %s: %v", host.URL, err)
-		return false, "", fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+
+	// Send the virtual host as Host header and TLS SNI when connecting by IP
+	if host.VirtualHost != "" {
+		req = req.WithContext(context.WithValue(req.Context(), serverNameKey{}, host.VirtualHost))
 	}
+	req.Host = c.hostHeader(req.URL, host.VirtualHost)
 
 	// Set headers to avoid blocking
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; CenseiBot/1.0)")
+	req.Header.Set("User-Agent", c.userAgents.Pick(host.URL))
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", c.acceptLang)
+
+	return req, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// FetchResult holds the outcome of fetching a host's root page
+
+type FetchResult struct {
+	Online      bool
+	Body        string
+	StatusCode  int
+	ContentType string
+	Server      string            // Server response header
+	Size        int               // Bytes of the body that were read
+	FinalURL    string            // URL of the last request after following redirects
+	Headers     map[string]string // Captured response headers, see SetCaptureHeaders
+
+	// TLS connection of HTTPS responses, including the peer certificates (nil for HTTP)
+	TLS *tls.ConnectionState
+}
+
+// CheckHostAndFetch combines checking if host is online and fetching its content
+// Returns if the host is online, the content (if any), its Content-Type, and any error
+func (c *Client) CheckHostAndFetch(ctx context.Context, host api.Host) (bool, string, string, error) {
+	result, err := c.FetchHost(ctx, host)
+	if err != nil {
+		return false, "", "", err
+	}
+	return result.Online, result.Body, result.ContentType, nil
+}
+
+// FetchHost checks if a host is online and fetches its content along with response details
+// Hosts answering 429 or 503 are paused and retried if throttle retries are enabled
+func (c *Client) FetchHost(ctx context.Context, host api.Host) (*FetchResult, error) {
+	for attempt := 0; ; attempt++ {
+		c.throttle.wait(ctx, host.URL)
+		if err := c.rateLimiter.wait(ctx, host.URL); err != nil {
+			return nil, err
+		}
+
+		result, retryAfter, err := c.fetchHost(ctx, host)
+		if err != nil || !isThrottleStatus(result.StatusCode) || attempt >= c.throttleRetries || ctx.Err() != nil {
+			return result, err
+		}
+
+		pause := c.throttle.pause(host.URL, retryAfter)
+		c.logger.Info("Host is rate limiting: %s (Status: %d) - pausing requests to it for %v (retry %d/%d)",
+			host.URL, result.StatusCode, pause.Round(time.Second), attempt+1, c.throttleRetries)
+	}
+}
+
+// fetchHost performs a single fetch of a host
+// Also returns the Retry-After delay of throttling responses
+func (c *Client) fetchHost(ctx context.Context, host api.Host) (*FetchResult, time.Duration, error) {
+	c.logger.Debug("Checking host and fetching content: %s", host.URL)
+	result := &FetchResult{}
+
+	// Ranged continuation fetches get their own timeout per chunk, see fetchRemainingChunks
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, c.httpClient.Timeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, host)
+	if err != nil {
+		c.logger.Error("Failed to create HTTP request for %s: %v", host.URL, err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
 	if err != nil {
 		c.logger.Debug("Host offline or unreachable: %s (%s)", host.URL, err)
-		return false, "", nil // Not an error, just offline
+		return result, 0, nil // Not an error, just offline
 	}
 	defer resp.Body.Close()
 
+	result.StatusCode = resp.StatusCode
+	result.ContentType = resp.Header.Get("Content-Type")
+	result.Server = resp.Header.Get("Server")
+	result.FinalURL = resp.Request.URL.String()
+	result.TLS = resp.TLS
+	if len(c.captureHeaders) > 0 {
+		result.Headers = captureHeaders(resp.Header, c.captureHeaders)
+	}
+	if result.FinalURL != host.URL {
+		c.logger.Debug("Followed redirects from %s to %s", host.URL, result.FinalURL)
+	}
+
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
 		c.logger.Debug("Host responded with non-OK status: %s (Status: %d)", host.URL, resp.StatusCode)
-		return false, "", nil
+		return result, parseRetryAfter(resp.Header.Get("Retry-After")), nil
 	}
 
 	// Read the response body with size limit to prevent memory exhaustion
-	// Limit to 50 MB to handle large directory listings with thousands of files
-	// Typical directory listings: 1-100 KB, large ones: 5-20 MB, extreme cases: up to 50 MB
-	const maxBodySize = 50 << 20 // 50 MB
-	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	// Read one extra byte so hitting the limit can be told apart from a body of exactly the limit
+	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
 	if err != nil {
 		// Timeout errors for large directories (e.g., /calls-old/) are common
 		// Log as debug and continue - the host is online, just slow to respond
 		c.logger.Debug("Failed to read response body for %s: %v (skipping)", host.URL, err)
-		return true, "", nil // Return empty body, but mark host as online
+		result.Online = true // Return empty body, but mark host as online
+		return result, 0, nil
+	}
+
+	// Detect truncated listings so files past the cutoff are not silently lost
+	if len(bodyBytes) > maxBodySize {
+		bodyBytes = bodyBytes[:maxBodySize]
+		c.truncatedURLs.Store(host.URL, true)
+		c.logger.Error("WARNING: Response body for %s exceeded %d bytes, listing is truncated", host.URL, maxBodySize)
+
+		if c.maxChunks > 0 {
+			bodyBytes = c.fetchRemainingChunks(parent, host, bodyBytes)
+		}
 	}
 
 	c.logger.Debug("Host online: %s (Status: %d, Content length: %d bytes)",
 		host.URL, resp.StatusCode, len(bodyBytes))
 
-	return true, string(bodyBytes), nil
+	result.Online = true
+	result.Body = string(bodyBytes)
+	result.Size = len(bodyBytes)
+	return result, 0, nil
+}
+
+// PostJSON sends a JSON payload to a URL on the host and returns the response body
+// Used for file browsers that load their listing from a JSON API
+func (c *Client) PostJSON(ctx context.Context, host api.Host, targetURL string, payload []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.httpClient.Timeout)
+	defer cancel()
+
+	apiHost := host
+	apiHost.URL = targetURL
+	req, err := c.newRequest(ctx, apiHost)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Method = "POST"
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.ContentLength = int64(len(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("server returned non-OK status: %d", resp.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return string(bodyBytes), nil
+}
+
+// FetchRange performs a ranged GET request and returns up to maxBytes of the body
+// Servers that ignore the Range header are rejected to avoid downloading whole files
+func (c *Client) FetchRange(ctx context.Context, host api.Host, byteRange string, maxBytes int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.httpClient.Timeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Range", byteRange)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("server does not support range requests (Status: %d)", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxBytes))
+}
+
+// hostHeader builds the Host header value for a request URL
+// Returns an empty string to keep the default (URL host including port)
+func (c *Client) hostHeader(requestURL *url.URL, virtualHost string) string {
+	if virtualHost == "" && !c.stripHostPort {
+		return ""
+	}
+
+	hostName := requestURL.Hostname()
+	if virtualHost != "" {
+		hostName = virtualHost
+	}
+
+	port := requestURL.Port()
+	if port != "" && !c.stripHostPort {
+		return net.JoinHostPort(hostName, port)
+	}
+
+	// Bare IPv6 addresses still need brackets without a port
+	if strings.Contains(hostName, ":") {
+		return "[" + hostName + "]"
+	}
+	return hostName
+}
+
+// fetchRemainingChunks continues reading a truncated listing using HTTP range requests
+// Stops when the server does not honor ranges, the listing is complete or the chunk limit is hit
+func (c *Client) fetchRemainingChunks(parent context.Context, host api.Host, body []byte) []byte {
+	for chunk := 1; chunk <= c.maxChunks; chunk++ {
+		offset := len(body)
+
+		ctx, cancel := context.WithTimeout(parent, c.httpClient.Timeout)
+		req, err := c.newRequest(ctx, host)
+		if err != nil {
+			cancel()
+			return body
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+maxBodySize-1))
+
+		resp, err := c.do(req)
+		if err != nil {
+			cancel()
+			c.logger.Debug("Ranged fetch failed for %s at offset %d: %v", host.URL, offset, err)
+			return body
+		}
+
+		// Servers that ignore the Range header send the full body again
+		if resp.StatusCode != http.StatusPartialContent {
+			resp.Body.Close()
+			cancel()
+			c.logger.Debug("Server does not support ranged fetches for %s (Status: %d)", host.URL, resp.StatusCode)
+			return body
+		}
+
+		chunkBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		resp.Body.Close()
+		cancel()
+		if err != nil {
+			c.logger.Debug("Failed to read ranged chunk for %s at offset %d: %v", host.URL, offset, err)
+			return body
+		}
+
+		body = append(body, chunkBytes...)
+		c.logger.Info("Fetched listing chunk %d for %s (%d bytes, total %d bytes)", chunk, host.URL, len(chunkBytes), len(body))
+
+		// A short chunk means the end of the listing was reached
+		if len(chunkBytes) < maxBodySize {
+			c.truncatedURLs.Delete(host.URL)
+			c.logger.Info("Listing for %s read completely using ranged fetches", host.URL)
+			return body
+		}
+	}
+
+	c.logger.Error("WARNING: Listing for %s still truncated after %d ranged fetches", host.URL, c.maxChunks)
+	return body
+}
+
+// GetTruncatedURLs returns all URLs whose listing could not be read completely
+func (c *Client) GetTruncatedURLs() []string {
+	urls := []string{}
+	c.truncatedURLs.Range(func(key, value interface{}) bool {
+		urls = append(urls, key.(string))
+		return true
+	})
+	sort.Strings(urls)
+	return urls
+}
+
+// dialContext opens a TCP connection directly or through the proxy
+func (c *Client) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.proxyDialer != nil {
+		return c.proxyDialer.DialContext(ctx, network, addr)
+	}
+	return c.dialer.DialContext(ctx, network, addr)
+}
+
+// dialTLS establishes a TLS connection using the SNI from the request context if present
+// Falls back to the dialed host like the default transport does
+// nextProtos sets the ALPN protocols offered during the handshake (nil = HTTP/1.1 only)
+func dialTLS(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), network, addr string, nextProtos []string) (net.Conn, error) {
+	conn, err := dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	serverName, _ := ctx.Value(serverNameKey{}).(string)
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(addr)
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		InsecureSkipVerify: true, // Skip SSL certificate verification
+		ServerName:         serverName,
+		NextProtos:         nextProtos,
+	})
+
+	handshakeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
 }
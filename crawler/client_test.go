@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"censei/api"
+	"censei/logging"
+)
+
+func TestFetchHostKeepsVirtualHostConnectionsApart(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.ServerName))
+	}))
+	defer server.Close()
+
+	client := NewClient(5, logging.NewLogger())
+	defer client.CloseIdleConnections()
+
+	// Alternate the virtual hosts so a pooled connection would be picked up by the other one
+	for _, virtualHost := range []string{"a.example", "b.example", "a.example", "b.example"} {
+		result, err := client.FetchHost(context.Background(), api.Host{URL: server.URL, VirtualHost: virtualHost})
+		if err != nil {
+			t.Fatalf("FetchHost(%s) error: %v", virtualHost, err)
+		}
+		if result.Body != virtualHost {
+			t.Errorf("FetchHost(%s) was sent with SNI %q", virtualHost, result.Body)
+		}
+	}
+}
+
+// startConnectProxy runs an HTTP proxy that tunnels CONNECT requests and reports
+// the SNI it was reached with (empty for plain HTTP proxies)
+func startConnectProxy(t *testing.T, useTLS bool) (string, <-chan string) {
+	proxySNI := make(chan string, 16)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer target.Close()
+
+		serverName := ""
+		if r.TLS != nil {
+			serverName = r.TLS.ServerName
+		}
+		proxySNI <- serverName
+
+		conn, buffered, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+		go io.Copy(target, buffered)
+		io.Copy(conn, target)
+	})
+
+	server := httptest.NewUnstartedServer(handler)
+	if useTLS {
+		server.StartTLS()
+	} else {
+		server.Start()
+	}
+	t.Cleanup(server.Close)
+	return server.URL, proxySNI
+}
+
+func TestFetchHostSendsVirtualHostThroughProxy(t *testing.T) {
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.ServerName + " " + r.Proto))
+	}))
+	target.EnableHTTP2 = true
+	target.StartTLS()
+	defer target.Close()
+
+	for _, scheme := range []string{"http", "https"} {
+		t.Run(scheme, func(t *testing.T) {
+			proxyURL, proxySNI := startConnectProxy(t, scheme == "https")
+			if !strings.HasPrefix(proxyURL, scheme+"://") {
+				t.Fatalf("proxy URL %s has the wrong scheme", proxyURL)
+			}
+
+			client := NewClient(5, logging.NewLogger())
+			defer client.CloseIdleConnections()
+			client.SetHTTP2(true)
+			if err := client.SetProxy(proxyURL); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := client.FetchHost(context.Background(), api.Host{URL: target.URL, VirtualHost: "a.example"})
+			if err != nil {
+				t.Fatalf("FetchHost() error: %v", err)
+			}
+			if result.Body != "a.example HTTP/2.0" {
+				t.Errorf("target saw %q, want SNI a.example over HTTP/2", result.Body)
+			}
+
+			select {
+			case serverName := <-proxySNI:
+				if serverName == "a.example" {
+					t.Error("virtual host was sent as SNI to the proxy")
+				}
+			default:
+				t.Error("request did not go through the proxy")
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"censei/api"
+	"censei/logging"
+	"censei/netproxy"
+)
+
+// maxFTPListingSize limits a single FTP directory listing (10 MB)
+const maxFTPListingSize = 10 << 20
+
+// FTPLister lists files on anonymous FTP servers
+type FTPLister struct {
+	timeout time.Duration
+	logger  *logging.Logger
+	dialer  netproxy.ContextDialer
+}
+
+// ftpSession is an open control connection to an FTP server
+type ftpSession struct {
+	conn    *textproto.Conn
+	rawConn net.Conn
+	address string
+	timeout time.Duration
+	dialer  netproxy.ContextDialer
+}
+
+// NewFTPLister creates a new FTP lister with the specified timeout
+func NewFTPLister(timeoutSeconds int, logger *logging.Logger) *FTPLister {
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	return &FTPLister{
+		timeout: timeout,
+		logger:  logger,
+		dialer:  &net.Dialer{Timeout: timeout},
+	}
+}
+
+// SetProxy routes control and data connections through a SOCKS5 proxy
+func (f *FTPLister) SetProxy(proxyURL string) error {
+	dialer, err := socksDialer(proxyURL, &net.Dialer{Timeout: f.timeout})
+	if err != nil {
+		return err
+	}
+	f.dialer = dialer
+	return nil
+}
+
+// ListFiles logs in anonymously and returns ftp:// URLs of all files up to maxDepth levels deep
+// Directories are only descended into when the server supports MLSD
+func (f *FTPLister) ListFiles(ctx context.Context, host api.Host, maxDepth int, maxFiles int) ([]string, error) {
+	address := host.IP
+	if address == "" {
+		address = host.BaseAddress
+	}
+
+	session, err := f.connect(ctx, net.JoinHostPort(address, strconv.Itoa(host.Port)))
+	if err != nil {
+		return nil, err
+	}
+	defer session.close()
+
+	// Unblock reads in progress when the scan is stopped
+	stop := context.AfterFunc(ctx, func() { session.rawConn.Close() })
+	defer stop()
+
+	var files []string
+	dirs := []string{"/"}
+	for depth := 1; depth <= maxDepth && len(dirs) > 0; depth++ {
+		var nextDirs []string
+		for _, dir := range dirs {
+			if ctx.Err() != nil {
+				return files, ctx.Err()
+			}
+
+			entries, subDirs, err := session.list(ctx, dir)
+			if err != nil {
+				f.logger.Debug("Failed to list FTP directory %s%s: %v", host.URL, dir, err)
+				continue
+			}
+
+			for _, entry := range entries {
+				files = append(files, host.URL+(&url.URL{Path: entry}).EscapedPath())
+				if maxFiles > 0 && len(files) >= maxFiles {
+					f.logger.Info("Reached max files (%d) for FTP host %s", maxFiles, host.URL)
+					return files, nil
+				}
+			}
+			nextDirs = append(nextDirs, subDirs...)
+		}
+		dirs = nextDirs
+	}
+
+	return files, nil
+}
+
+// connect opens the control connection and performs the anonymous login
+func (f *FTPLister) connect(ctx context.Context, address string) (*ftpSession, error) {
+	rawConn, err := f.dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	rawConn.SetDeadline(time.Now().Add(f.timeout * 6))
+
+	session := &ftpSession{
+		conn:    textproto.NewConn(rawConn),
+		rawConn: rawConn,
+		address: address,
+		timeout: f.timeout,
+		dialer:  f.dialer,
+	}
+
+	if _, _, err := session.conn.ReadResponse(220); err != nil {
+		session.close()
+		return nil, fmt.Errorf("unexpected greeting: %w", err)
+	}
+
+	code, _, err := session.command("USER anonymous")
+	if err == nil && code == 331 {
+		code, _, err = session.command("PASS anonymous@")
+	}
+	if err != nil || code != 230 {
+		session.close()
+		return nil, fmt.Errorf("anonymous login rejected (code %d): %v", code, err)
+	}
+
+	return session, nil
+}
+
+// command sends a command and reads the final response
+func (s *ftpSession) command(format string, args ...interface{}) (int, string, error) {
+	if err := s.conn.PrintfLine(format, args...); err != nil {
+		return 0, "", err
+	}
+	return s.conn.ReadResponse(0)
+}
+
+// close ends the session, ignoring errors from servers that drop the connection early
+func (s *ftpSession) close() {
+	s.conn.PrintfLine("QUIT")
+	s.conn.Close()
+}
+
+// list returns file paths and subdirectory paths of a directory
+// Uses MLSD for typed entries and falls back to NLST (files only, no recursion)
+func (s *ftpSession) list(ctx context.Context, dir string) ([]string, []string, error) {
+	data, err := s.transfer(ctx, "MLSD "+dir)
+	if err == nil {
+		files, dirs := parseMLSD(dir, data)
+		return files, dirs, nil
+	}
+
+	data, err = s.transfer(ctx, "NLST "+dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		name := strings.TrimSpace(line)
+		if name == "" || name == "." || name == ".." {
+			continue
+		}
+		// Some servers return full paths, others bare names
+		if !strings.HasPrefix(name, "/") {
+			name = path.Join(dir, name)
+		}
+		files = append(files, name)
+	}
+	return files, nil, nil
+}
+
+// transfer runs a listing command over a passive data connection and returns its output
+func (s *ftpSession) transfer(ctx context.Context, cmd string) ([]byte, error) {
+	dataAddress, err := s.passiveAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	dataConn, err := s.dialer.DialContext(ctx, "tcp", dataAddress)
+	if err != nil {
+		return nil, fmt.Errorf("data connection failed: %w", err)
+	}
+	defer dataConn.Close()
+	dataConn.SetDeadline(time.Now().Add(s.timeout * 6))
+	stop := context.AfterFunc(ctx, func() { dataConn.Close() })
+	defer stop()
+
+	code, msg, err := s.command("%s", cmd)
+	if err != nil || (code != 125 && code != 150) {
+		return nil, fmt.Errorf("%s rejected (code %d): %s", strings.Fields(cmd)[0], code, msg)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(dataConn, maxFTPListingSize))
+	dataConn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read listing: %w", err)
+	}
+
+	if _, _, err := s.conn.ReadResponse(2); err != nil {
+		return nil, fmt.Errorf("transfer not completed: %w", err)
+	}
+	return data, nil
+}
+
+// passiveAddress requests a passive data port (EPSV first, then PASV)
+// The control connection host is always used, as servers behind NAT often report private IPs
+func (s *ftpSession) passiveAddress() (string, error) {
+	host, _, _ := net.SplitHostPort(s.address)
+
+	code, msg, err := s.command("EPSV")
+	if err == nil && code == 229 {
+		// Format: Entering Extended Passive Mode (|||port|)
+		start := strings.Index(msg, "(")
+		end := strings.LastIndex(msg, ")")
+		if start >= 0 && end > start {
+			fields := strings.Split(msg[start+1:end], "|")
+			if len(fields) >= 4 {
+				if port, err := strconv.Atoi(fields[3]); err == nil {
+					return net.JoinHostPort(host, strconv.Itoa(port)), nil
+				}
+			}
+		}
+	}
+
+	code, msg, err = s.command("PASV")
+	if err != nil || code != 227 {
+		return "", fmt.Errorf("passive mode rejected (code %d): %s", code, msg)
+	}
+
+	// Format: Entering Passive Mode (h1,h2,h3,h4,p1,p2)
+	start := strings.Index(msg, "(")
+	end := strings.LastIndex(msg, ")")
+	if start < 0 || end <= start {
+		return "", fmt.Errorf("invalid PASV response: %s", msg)
+	}
+	fields := strings.Split(msg[start+1:end], ",")
+	if len(fields) != 6 {
+		return "", fmt.Errorf("invalid PASV response: %s", msg)
+	}
+	p1, err1 := strconv.Atoi(strings.TrimSpace(fields[4]))
+	p2, err2 := strconv.Atoi(strings.TrimSpace(fields[5]))
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("invalid PASV port: %s", msg)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(p1*256+p2)), nil
+}
+
+// parseMLSD splits MLSD output ("type=file;size=12; name") into file and directory paths
+func parseMLSD(dir string, data []byte) ([]string, []string) {
+	var files, dirs []string
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		separator := strings.Index(line, " ")
+		if separator < 0 {
+			continue
+		}
+		facts := strings.ToLower(line[:separator])
+		name := line[separator+1:]
+		fullPath := path.Join(dir, name)
+
+		switch {
+		case strings.Contains(facts, "type=file"):
+			files = append(files, fullPath)
+		case strings.Contains(facts, "type=dir"):
+			dirs = append(dirs, fullPath)
+		}
+	}
+
+	return files, dirs
+}
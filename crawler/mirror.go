@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"censei/api"
+)
+
+// listingFingerprint hashes the sorted entry names of a listing relative to the host URL
+// Mirrors serve the same names under a different host, so the host part is stripped
+// Returns "" for listings without entries, which are too common to tell mirrors apart
+func (w *Worker) listingFingerprint(host api.Host, htmlContent string, contentType string) string {
+	fileURLs := w.directoryScanner.ScanHost(host, htmlContent, contentType)
+	if len(fileURLs) == 0 {
+		return ""
+	}
+
+	prefix := strings.TrimSuffix(host.URL, "/") + "/"
+	names := make([]string, 0, len(fileURLs))
+	for _, fileURL := range fileURLs {
+		names = append(names, strings.TrimPrefix(fileURL, prefix))
+	}
+	sort.Strings(names)
+
+	sum := sha256.Sum256([]byte(strings.Join(names, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// skipMirror checks if the root listing of host is identical to one seen on another host
+// The first host with a fingerprint is scanned, later ones are recorded as mirrors of it
+func (w *Worker) skipMirror(host api.Host, htmlContent string, contentType string) bool {
+	fingerprint := w.listingFingerprint(host, htmlContent, contentType)
+	if fingerprint == "" {
+		return false
+	}
+
+	original, loaded := w.mirrorFingerprints.LoadOrStore(fingerprint, host.URL)
+	if !loaded || original.(string) == host.URL {
+		return false
+	}
+
+	atomic.AddInt64(&w.mirrorHosts, 1)
+	w.logger.Info("Skipping host - mirror of %s: %s", original, host.URL)
+	if err := w.writer.WriteRawOutput(fmt.Sprintf("Mirror listing: %s (mirror of %s)", host.URL, original)); err != nil {
+		w.logger.Error("Failed to write raw output for mirror %s: %v", host.URL, err)
+		atomic.AddInt64(&w.stats.writeErrors, 1)
+	}
+	return true
+}
+
+// GetMirrorHosts returns the number of hosts skipped as mirrors with skip_mirror_hosts
+func (w *Worker) GetMirrorHosts() int {
+	return int(atomic.LoadInt64(&w.mirrorHosts))
+}
@@ -0,0 +1,28 @@
+package crawler
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"censei/netproxy"
+)
+
+// socksDialer returns a dialer that connects through a SOCKS5 proxy
+// Only SOCKS5 can carry raw TCP; HTTP proxies are rejected with the config
+func socksDialer(proxyURL string, forward *net.Dialer) (netproxy.ContextDialer, error) {
+	parsedURL, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if !strings.HasPrefix(parsedURL.Scheme, "socks5") {
+		return nil, fmt.Errorf("proxy not usable for FTP/SMB: %s", parsedURL.Scheme)
+	}
+
+	dialer, err := netproxy.New(proxyURL, forward)
+	if err != nil {
+		return nil, fmt.Errorf("proxy not usable for FTP/SMB: %w", err)
+	}
+	return dialer, nil
+}
@@ -0,0 +1,89 @@
+package crawler
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"censei/api"
+	"censei/logging"
+)
+
+// startSOCKS5 runs a SOCKS5 server without authentication that reports the
+// destination of each CONNECT request and then drops the connection
+func startSOCKS5(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	destinations := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				greeting := make([]byte, 2)
+				if _, err := io.ReadFull(conn, greeting); err != nil {
+					return
+				}
+				io.CopyN(io.Discard, conn, int64(greeting[1]))
+				conn.Write([]byte{0x05, 0x00}) // No authentication
+
+				// VER CMD RSV ATYP, IPv4 address and port
+				request := make([]byte, 10)
+				if _, err := io.ReadFull(conn, request); err != nil || request[3] != 0x01 {
+					return
+				}
+				ip := net.IP(request[4:8])
+				port := binary.BigEndian.Uint16(request[8:10])
+				destinations <- net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+			}()
+		}
+	}()
+	return listener.Addr().String(), destinations
+}
+
+func TestProtocolListersDialThroughProxy(t *testing.T) {
+	listers := map[string]protocolLister{
+		"ftp": NewFTPLister(5, logging.NewLogger()),
+		"smb": NewSMBLister(5, logging.NewLogger()),
+	}
+
+	for protocol, lister := range listers {
+		t.Run(protocol, func(t *testing.T) {
+			proxyAddress, destinations := startSOCKS5(t)
+			if err := lister.SetProxy("socks5://" + proxyAddress); err != nil {
+				t.Fatal(err)
+			}
+
+			host := api.Host{IP: "192.0.2.10", Port: 2121, Protocol: protocol, URL: protocol + "://192.0.2.10:2121"}
+			if _, err := lister.ListFiles(context.Background(), host, 1, 0); err == nil {
+				t.Error("ListFiles() succeeded although the proxy dropped the connection")
+			}
+
+			select {
+			case destination := <-destinations:
+				if destination != "192.0.2.10:2121" {
+					t.Errorf("proxy was asked to connect to %s, want 192.0.2.10:2121", destination)
+				}
+			default:
+				t.Error("connection did not go through the proxy")
+			}
+		})
+	}
+}
+
+func TestSetProxyRejectsHTTPProxies(t *testing.T) {
+	lister := NewFTPLister(5, logging.NewLogger())
+	if err := lister.SetProxy("http://127.0.0.1:3128"); err == nil {
+		t.Error("SetProxy() accepted an HTTP proxy for FTP")
+	}
+}
@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTimeout is how long a host's limiter is kept after its last request
+// Limiters are only dropped once their bucket has refilled, so dropping one never allows an early request
+const rateLimiterIdleTimeout = time.Minute
+
+// hostRateLimiter spaces out requests to the same base host
+// A nil hostRateLimiter never waits
+type hostRateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	limiters  map[string]*hostLimiter // base host -> limiter
+	lastSweep time.Time
+}
+
+// hostLimiter is the limiter of one base host
+type hostLimiter struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// newHostRateLimiter creates a limiter allowing requestsPerSecond requests per base host
+// Returns nil (unlimited) if requestsPerSecond is not positive
+func newHostRateLimiter(requestsPerSecond float64) *hostRateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &hostRateLimiter{
+		limit:     rate.Limit(requestsPerSecond),
+		limiters:  make(map[string]*hostLimiter),
+		lastSweep: time.Now(),
+	}
+}
+
+// wait blocks until a request to the base host of rawURL is allowed
+// Returns the context error if ctx is cancelled while waiting
+func (l *hostRateLimiter) wait(ctx context.Context, rawURL string) error {
+	if l == nil {
+		return nil
+	}
+
+	return l.get(throttleKey(rawURL)).Wait(ctx)
+}
+
+// get returns the limiter of a base host, creating it on first use
+// Idle limiters are swept at most once per rateLimiterIdleTimeout
+func (l *hostRateLimiter) get(key string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= rateLimiterIdleTimeout {
+		l.evictIdle(now)
+	}
+
+	entry, ok := l.limiters[key]
+	if !ok {
+		entry = &hostLimiter{limiter: rate.NewLimiter(l.limit, 1)}
+		l.limiters[key] = entry
+	}
+	entry.lastUsed = now
+	return entry.limiter
+}
+
+// evictIdle drops limiters that were not used recently and have a full bucket
+// Caller must hold mu
+func (l *hostRateLimiter) evictIdle(now time.Time) {
+	for key, entry := range l.limiters {
+		if now.Sub(entry.lastUsed) >= rateLimiterIdleTimeout && entry.limiter.TokensAt(now) >= 1 {
+			delete(l.limiters, key)
+		}
+	}
+	l.lastSweep = now
+}
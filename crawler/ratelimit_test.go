@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestHostRateLimiterSpacesRequestsPerHost(t *testing.T) {
+	limiter := newHostRateLimiter(20) // One request every 50ms
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.wait(ctx, "http://a.example/file"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 requests to one host took %v, want at least 100ms", elapsed)
+	}
+
+	// Other hosts have their own budget
+	start = time.Now()
+	if err := limiter.wait(ctx, "http://b.example/"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("first request to another host waited %v", elapsed)
+	}
+}
+
+func TestHostRateLimiterStopsOnCancel(t *testing.T) {
+	limiter := newHostRateLimiter(0.1) // One request every 10s
+	limiter.wait(context.Background(), "http://a.example/")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.wait(ctx, "http://a.example/"); err == nil {
+		t.Error("wait() returned nil although the context expired first")
+	}
+}
+
+func TestHostRateLimiterEvictsIdleHosts(t *testing.T) {
+	limiter := newHostRateLimiter(0.1)
+	past := time.Now().Add(-2 * rateLimiterIdleTimeout)
+	limiter.lastSweep = past
+
+	// Both idle, but busy.example still has a request pending in its bucket
+	limiter.limiters["idle.example"] = &hostLimiter{limiter: rate.NewLimiter(0.1, 1), lastUsed: past}
+	busy := rate.NewLimiter(0.1, 1)
+	busy.Allow()
+	limiter.limiters["busy.example"] = &hostLimiter{limiter: busy, lastUsed: past}
+
+	limiter.wait(context.Background(), "http://new.example/")
+
+	if _, ok := limiter.limiters["idle.example"]; ok {
+		t.Error("idle limiter was not evicted")
+	}
+	if _, ok := limiter.limiters["busy.example"]; !ok {
+		t.Error("limiter with an empty bucket was evicted")
+	}
+	if _, ok := limiter.limiters["new.example"]; !ok {
+		t.Error("limiter of the new host is missing")
+	}
+}
@@ -0,0 +1,195 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"censei/api"
+)
+
+// robotsAgent is the product token whose robots.txt group takes precedence over "*"
+const robotsAgent = "censei"
+
+// robotsRule is an Allow or Disallow line of robots.txt
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsRules are the robots.txt rules that apply to Censei on one host
+// A nil robotsRules allows everything
+type robotsRules struct {
+	rules []robotsRule
+}
+
+// disallowAllRobots is used for hosts whose robots.txt is unavailable due to server errors
+var disallowAllRobots = &robotsRules{rules: []robotsRule{{pattern: "/", allow: false}}}
+
+// parseRobots reads the group for "censei" from a robots.txt, or the "*" group if there is none
+// Consecutive User-agent lines share one group, as defined in RFC 9309
+func parseRobots(content string) *robotsRules {
+	groups := make(map[string][]robotsRule)
+	var agents []string
+	inRules := false
+
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A User-agent line after rules starts a new group
+			if inRules {
+				agents = nil
+				inRules = false
+			}
+			agent := strings.ToLower(value)
+			agents = append(agents, agent)
+			if _, ok := groups[agent]; !ok {
+				groups[agent] = nil
+			}
+		case "allow", "disallow":
+			inRules = true
+			// An empty Disallow allows everything and adds no rule
+			if value == "" {
+				continue
+			}
+			for _, agent := range agents {
+				groups[agent] = append(groups[agent], robotsRule{pattern: value, allow: key == "allow"})
+			}
+		}
+	}
+
+	if rules, ok := groups[robotsAgent]; ok {
+		return &robotsRules{rules: rules}
+	}
+	return &robotsRules{rules: groups["*"]}
+}
+
+// allowed reports whether a URL path (with query) may be fetched
+// The longest matching rule wins, Allow wins over an equally long Disallow
+func (r *robotsRules) allowed(path string) bool {
+	if r == nil || path == "/robots.txt" {
+		return true
+	}
+
+	allowed := true
+	matchLength := -1
+	for _, rule := range r.rules {
+		if !robotsPatternMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > matchLength || (len(rule.pattern) == matchLength && rule.allow) {
+			allowed = rule.allow
+			matchLength = len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// robotsPatternMatch matches a path against a robots.txt pattern
+// "*" matches any sequence of characters and a trailing "$" anchors the pattern at the end
+func robotsPatternMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	path = path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || path == ""
+	}
+
+	// Middle parts match at their first occurrence, the last one may have to end the path
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(path, part)
+		if index < 0 {
+			return false
+		}
+		path = path[index+len(part):]
+	}
+
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(path, last)
+	}
+	return strings.Contains(path, last)
+}
+
+// robotsAllowed checks if robots.txt of the host allows fetching host.URL
+// Always true unless respect_robots is enabled
+func (w *Worker) robotsAllowed(ctx context.Context, host api.Host) bool {
+	if !w.config.RespectRobots {
+		return true
+	}
+
+	parsedURL, err := url.Parse(host.URL)
+	if err != nil {
+		return true
+	}
+
+	path := parsedURL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if parsedURL.RawQuery != "" {
+		path += "?" + parsedURL.RawQuery
+	}
+
+	return w.robotsRulesFor(ctx, host, parsedURL).allowed(path)
+}
+
+// robotsRulesFor returns the cached robots.txt rules of a host, fetching them once per base host
+// Missing robots.txt files allow everything, server errors disallow everything (RFC 9309)
+func (w *Worker) robotsRulesFor(ctx context.Context, host api.Host, parsedURL *url.URL) *robotsRules {
+	origin := parsedURL.Scheme + "://" + parsedURL.Host
+	if cached, ok := w.robotsCache.Load(origin); ok {
+		return cached.(*robotsRules)
+	}
+
+	robotsHost := api.Host{URL: origin + "/robots.txt", VirtualHost: host.VirtualHost}
+	result, err := w.client.FetchHost(ctx, robotsHost)
+
+	var rules *robotsRules
+	switch {
+	case err != nil || ctx.Err() != nil:
+		// Unreachable hosts are not cached, a later request may succeed
+		w.logger.Debug("Failed to fetch robots.txt for %s: %v", origin, err)
+		return nil
+	case result.StatusCode >= http.StatusInternalServerError:
+		w.logger.Debug("robots.txt of %s unavailable (Status: %d), treating host as disallowed", origin, result.StatusCode)
+		rules = disallowAllRobots
+	case result.Online:
+		rules = parseRobots(result.Body)
+		w.logger.Debug("Loaded robots.txt for %s (%d rules)", origin, len(rules.rules))
+	default:
+		rules = &robotsRules{}
+	}
+
+	actual, _ := w.robotsCache.LoadOrStore(origin, rules)
+	return actual.(*robotsRules)
+}
+
+// robotsClient fetches listing directories during recursive scans only if robots.txt allows it
+type robotsClient struct {
+	worker *Worker
+}
+
+// CheckHostAndFetch reports directories disallowed by robots.txt as offline without fetching them
+func (c robotsClient) CheckHostAndFetch(ctx context.Context, host api.Host) (bool, string, string, error) {
+	if !c.worker.robotsAllowed(ctx, host) {
+		c.worker.logger.Debug("Skipping directory disallowed by robots.txt: %s", host.URL)
+		return false, "", "", nil
+	}
+	return c.worker.client.CheckHostAndFetch(ctx, host)
+}
@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"censei/output"
+)
+
+// countLinks adds links found on a host to the total of its base host for the scan report
+func (w *Worker) countLinks(hostURL string, links int) {
+	if links == 0 {
+		return
+	}
+	countPtr, _ := w.linkTotals.LoadOrStore(w.extractBaseHost(hostURL), new(int64))
+	atomic.AddInt64(countPtr.(*int64), int64(links))
+}
+
+// ScanReport collects the blocked and skipped hosts, skip counters and per-base-host link
+// totals of the scan; hosts are sorted by count (descending), then by name
+func (w *Worker) ScanReport() output.ScanReport {
+	report := output.ScanReport{
+		Limits: output.ScanLimits{
+			MaxLinksPerDirectory: w.config.MaxLinksPerDirectory,
+			MaxTotalLinks:        w.config.MaxTotalLinks,
+			MaxTotalLinksGlobal:  w.config.MaxTotalLinksGlobal,
+			MaxSkipsBeforeBlock:  w.config.MaxSkipsBeforeBlock,
+		},
+		BlockedHosts: []output.HostSkips{},
+		SkipCounts:   []output.HostSkips{},
+		SkippedHosts: []string{},
+		LinkTotals:   []output.HostLinks{},
+	}
+
+	w.skipCounters.Range(func(key, value interface{}) bool {
+		skips := output.HostSkips{BaseHost: key.(string), Skips: int(atomic.LoadInt64(value.(*int64)))}
+		report.SkipCounts = append(report.SkipCounts, skips)
+		if _, blocked := w.blockedHosts.Load(skips.BaseHost); blocked {
+			report.BlockedHosts = append(report.BlockedHosts, skips)
+		}
+		return true
+	})
+	w.skippedHosts.Range(func(key, _ interface{}) bool {
+		report.SkippedHosts = append(report.SkippedHosts, key.(string))
+		return true
+	})
+	w.linkTotals.Range(func(key, value interface{}) bool {
+		report.LinkTotals = append(report.LinkTotals, output.HostLinks{BaseHost: key.(string), Links: int(atomic.LoadInt64(value.(*int64)))})
+		return true
+	})
+
+	sortHostSkips(report.BlockedHosts)
+	sortHostSkips(report.SkipCounts)
+	sort.Strings(report.SkippedHosts)
+	sort.Slice(report.LinkTotals, func(i, j int) bool {
+		if report.LinkTotals[i].Links != report.LinkTotals[j].Links {
+			return report.LinkTotals[i].Links > report.LinkTotals[j].Links
+		}
+		return report.LinkTotals[i].BaseHost < report.LinkTotals[j].BaseHost
+	})
+	return report
+}
+
+// sortHostSkips orders base hosts by skips (descending), then by name
+func sortHostSkips(hosts []output.HostSkips) {
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Skips != hosts[j].Skips {
+			return hosts[i].Skips > hosts[j].Skips
+		}
+		return hosts[i].BaseHost < hosts[j].BaseHost
+	})
+}
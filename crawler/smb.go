@@ -0,0 +1,169 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"censei/api"
+	"censei/logging"
+	"censei/netproxy"
+
+	"github.com/hirochachacha/go-smb2"
+)
+
+// smbGuestUser is the account used to log in without credentials
+// go-smb2 has no null sessions; servers that allow anonymous access also accept the guest account
+const smbGuestUser = "Guest"
+
+// SMBLister enumerates shares and files on SMB servers using a guest session
+type SMBLister struct {
+	timeout time.Duration
+	logger  *logging.Logger
+	dialer  netproxy.ContextDialer
+}
+
+// smbShareFS is the part of a mounted share used to walk it
+type smbShareFS interface {
+	ReadDir(dirname string) ([]os.FileInfo, error)
+}
+
+// NewSMBLister creates a new SMB lister with the specified timeout
+func NewSMBLister(timeoutSeconds int, logger *logging.Logger) *SMBLister {
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	return &SMBLister{
+		timeout: timeout,
+		logger:  logger,
+		dialer:  &net.Dialer{Timeout: timeout},
+	}
+}
+
+// SetProxy routes connections through a SOCKS5 proxy
+func (s *SMBLister) SetProxy(proxyURL string) error {
+	dialer, err := socksDialer(proxyURL, &net.Dialer{Timeout: s.timeout})
+	if err != nil {
+		return err
+	}
+	s.dialer = dialer
+	return nil
+}
+
+// ListFiles enumerates readable disk shares and returns smb:// URLs of their files
+// Only guest access is attempted; no credentials are sent
+func (s *SMBLister) ListFiles(ctx context.Context, host api.Host, maxDepth int, maxFiles int) (files []string, err error) {
+	address := host.IP
+	if address == "" {
+		address = host.BaseAddress
+	}
+
+	// Bound the whole listing, a large share can otherwise keep a worker busy indefinitely
+	ctx, cancel := context.WithTimeout(ctx, s.timeout*12)
+	defer cancel()
+
+	conn, err := s.dialer.DialContext(ctx, "tcp", net.JoinHostPort(address, strconv.Itoa(host.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock reads in progress when the scan is stopped or the listing times out
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// go-smb2 trusts offsets in server responses, don't let a hostile server crash the scan
+	defer func() {
+		if r := recover(); r != nil {
+			files, err = nil, fmt.Errorf("malformed SMB response: %v", r)
+		}
+	}()
+
+	dialer := &smb2.Dialer{Initiator: &smb2.NTLMInitiator{User: smbGuestUser}}
+	session, err := dialer.DialContext(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("guest login rejected: %w", err)
+	}
+	session = session.WithContext(ctx)
+	defer session.Logoff()
+
+	names, err := session.ListSharenames()
+	if err != nil {
+		return nil, fmt.Errorf("share enumeration failed: %w", err)
+	}
+	shares := diskShares(names)
+	s.logger.Debug("SMB host %s exposes %d shares: %v", host.URL, len(shares), shares)
+
+	for _, share := range shares {
+		mounted, err := session.Mount(share)
+		if err != nil {
+			s.logger.Debug("Share %s on %s not accessible: %v", share, host.URL, err)
+			continue
+		}
+		shareFiles, err := listShare(mounted.WithContext(ctx), maxDepth, maxFiles-len(files))
+		mounted.Umount()
+		if err != nil {
+			s.logger.Debug("Share %s on %s not accessible: %v", share, host.URL, err)
+			continue
+		}
+
+		for _, file := range shareFiles {
+			files = append(files, host.URL+(&url.URL{Path: "/" + share + file}).EscapedPath())
+		}
+		if maxFiles > 0 && len(files) >= maxFiles {
+			s.logger.Info("Reached max files (%d) for SMB host %s", maxFiles, host.URL)
+			break
+		}
+	}
+
+	return files, nil
+}
+
+// diskShares drops administrative and IPC shares (IPC$, ADMIN$, C$, print$)
+// Share names ending in "$" are hidden by convention; printer shares fail to list and are skipped later
+func diskShares(names []string) []string {
+	var shares []string
+	for _, name := range names {
+		if name != "" && !strings.HasSuffix(name, "$") {
+			shares = append(shares, name)
+		}
+	}
+	return shares
+}
+
+// listShare walks a share breadth-first and returns file paths relative to the share
+func listShare(share smbShareFS, maxDepth int, maxFiles int) ([]string, error) {
+	var files []string
+	dirs := []string{""}
+	for depth := 1; depth <= maxDepth && len(dirs) > 0; depth++ {
+		var nextDirs []string
+		for _, dir := range dirs {
+			entries, err := share.ReadDir(dir)
+			if err != nil {
+				if dir == "" {
+					return nil, err
+				}
+				continue
+			}
+
+			for _, entry := range entries {
+				entryPath := path.Join("/", strings.ReplaceAll(dir, `\`, "/"), entry.Name())
+				if entry.IsDir() {
+					nextDirs = append(nextDirs, strings.TrimPrefix(strings.ReplaceAll(entryPath, "/", `\`), `\`))
+					continue
+				}
+				files = append(files, entryPath)
+				if maxFiles > 0 && len(files) >= maxFiles {
+					return files, nil
+				}
+			}
+		}
+		dirs = nextDirs
+	}
+
+	return files, nil
+}
@@ -0,0 +1,123 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"censei/api"
+	"censei/logging"
+)
+
+// fakeFileInfo is a directory entry of fakeShare
+type fakeFileInfo struct {
+	name string
+	dir  bool
+}
+
+func (f fakeFileInfo) Name() string       { return f.name }
+func (f fakeFileInfo) Size() int64        { return 0 }
+func (f fakeFileInfo) Mode() os.FileMode  { return 0 }
+func (f fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (f fakeFileInfo) IsDir() bool        { return f.dir }
+func (f fakeFileInfo) Sys() interface{}   { return nil }
+
+// fakeShare maps backslash-separated directory paths to their entries
+type fakeShare map[string][]os.FileInfo
+
+func (s fakeShare) ReadDir(dirname string) ([]os.FileInfo, error) {
+	entries, ok := s[dirname]
+	if !ok {
+		return nil, errors.New("access denied")
+	}
+	return entries, nil
+}
+
+func TestDiskShares(t *testing.T) {
+	got := diskShares([]string{"IPC$", "ADMIN$", "C$", "print$", "public", "", "Backups 2024"})
+	want := []string{"public", "Backups 2024"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diskShares() = %q, want %q", got, want)
+	}
+}
+
+func TestListShare(t *testing.T) {
+	share := fakeShare{
+		"": {
+			fakeFileInfo{name: "readme.txt"},
+			fakeFileInfo{name: "docs", dir: true},
+			fakeFileInfo{name: "private", dir: true},
+		},
+		`docs`: {
+			fakeFileInfo{name: "report 2024.pdf"},
+			fakeFileInfo{name: "old", dir: true},
+		},
+		`docs\old`: {
+			fakeFileInfo{name: "archive.zip"},
+		},
+		// "private" is missing and fails to list like a directory without guest access
+	}
+
+	tests := []struct {
+		name     string
+		share    fakeShare
+		maxDepth int
+		maxFiles int
+		want     []string
+		wantErr  bool
+	}{
+		{"root only", share, 1, 0, []string{"/readme.txt"}, false},
+		{"recursive", share, 3, 0, []string{"/readme.txt", "/docs/report 2024.pdf", "/docs/old/archive.zip"}, false},
+		{"max files", share, 3, 2, []string{"/readme.txt", "/docs/report 2024.pdf"}, false},
+		{"unreadable root", fakeShare{}, 1, 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := listShare(tt.share, tt.maxDepth, tt.maxFiles)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("listShare() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listShare() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSMBListFilesStopsOnCancel(t *testing.T) {
+	// A server that accepts connections but never answers the negotiation
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+	host := api.Host{IP: address.IP.String(), Port: address.Port, Protocol: "smb", URL: "smb://" + address.String()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	lister := NewSMBLister(30, logging.NewLogger())
+	if _, err := lister.ListFiles(ctx, host, 1, 0); err == nil {
+		t.Error("ListFiles() succeeded against a silent server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ListFiles() returned after %v, want prompt return on cancellation", elapsed)
+	}
+}
@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// lockedStats is the previous mutex-guarded ScanStats layout, kept as a baseline
+type lockedStats struct {
+	totalHosts, onlineHosts, totalFiles, filteredFiles int
+	mu                                                 sync.Mutex
+}
+
+func (s *lockedStats) add(counter *int, n int) {
+	s.mu.Lock()
+	*counter += n
+	s.mu.Unlock()
+}
+
+// BenchmarkScanStats compares the atomic counters with the previous mutex under many
+// concurrent workers, each recording a host and its files and occasionally reading the totals
+// Run with -cpu 1,8,32 to see how contention grows with the worker count
+func BenchmarkScanStats(b *testing.B) {
+	b.Run("atomic", func(b *testing.B) {
+		w := &Worker{stats: &ScanStats{categoryCounts: make(map[string]int)}}
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				atomic.AddInt64(&w.stats.totalHosts, 1)
+				atomic.AddInt64(&w.stats.onlineHosts, 1)
+				atomic.AddInt64(&w.stats.totalFiles, 10)
+				atomic.AddInt64(&w.stats.filteredFiles, 2)
+				if i++; i%64 == 0 {
+					w.GetStats()
+				}
+			}
+		})
+	})
+
+	b.Run("mutex", func(b *testing.B) {
+		stats := &lockedStats{}
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				stats.add(&stats.totalHosts, 1)
+				stats.add(&stats.onlineHosts, 1)
+				stats.add(&stats.totalFiles, 10)
+				stats.add(&stats.filteredFiles, 2)
+				if i++; i%64 == 0 {
+					stats.mu.Lock()
+					_, _, _, _ = stats.totalHosts, stats.onlineHosts, stats.totalFiles, stats.filteredFiles
+					stats.mu.Unlock()
+				}
+			}
+		})
+	})
+}
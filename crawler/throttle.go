@@ -0,0 +1,110 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pause limits for hosts that throttle the scan
+const (
+	defaultThrottlePause = 30 * time.Second
+	maxThrottlePause     = 5 * time.Minute
+)
+
+// hostThrottle pauses requests to base hosts that answered with 429 or 503
+// A nil hostThrottle never pauses
+type hostThrottle struct {
+	mu           sync.Mutex
+	resumeAt     map[string]time.Time // base host -> time requests may resume
+	defaultPause time.Duration
+}
+
+// newHostThrottle creates a throttle using defaultPause when no Retry-After is sent
+func newHostThrottle(defaultPause time.Duration) *hostThrottle {
+	if defaultPause <= 0 {
+		defaultPause = defaultThrottlePause
+	}
+	return &hostThrottle{
+		resumeAt:     make(map[string]time.Time),
+		defaultPause: defaultPause,
+	}
+}
+
+// wait blocks until requests to the base host of rawURL may resume or ctx is cancelled
+func (t *hostThrottle) wait(ctx context.Context, rawURL string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	resumeAt, ok := t.resumeAt[throttleKey(rawURL)]
+	t.mu.Unlock()
+
+	if ok {
+		if delay := time.Until(resumeAt); delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// pause stops requests to the base host of rawURL for retryAfter (or the default pause)
+// Returns the pause that was applied
+func (t *hostThrottle) pause(rawURL string, retryAfter time.Duration) time.Duration {
+	if retryAfter <= 0 {
+		retryAfter = t.defaultPause
+	}
+	if retryAfter > maxThrottlePause {
+		retryAfter = maxThrottlePause
+	}
+
+	key := throttleKey(rawURL)
+	resumeAt := time.Now().Add(retryAfter)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Keep a longer pause set by a concurrent request
+	if existing, ok := t.resumeAt[key]; !ok || existing.Before(resumeAt) {
+		t.resumeAt[key] = resumeAt
+	}
+	return retryAfter
+}
+
+// throttleKey returns the base host a URL is throttled by
+func throttleKey(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(parsedURL.Hostname())
+}
+
+// isThrottleStatus reports whether a status code means the host is rate limiting us
+func isThrottleStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -1,16 +1,26 @@
 package crawler
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
+	"path"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"censei/api"
 	"censei/config"
 	"censei/filechecker"
 	"censei/filter"
 	"censei/logging"
+	"censei/notify"
 	"censei/output"
 	"censei/scanners"
 )
@@ -32,20 +42,87 @@ type Worker struct {
 	blockedHosts     *sync.Map // In-memory cache of blocked hosts
 	skipCounters     *sync.Map // Skip counters per base host
 	stats            *ScanStats
-	blocklist        *filter.Blocklist
+	blocklist        filter.BlockStore
+	skipList         *filter.SkipList
+	categorizer      *filter.Categorizer
+	protocolListers  map[string]protocolLister
 	processedCount   int64 // Atomic counter for progress tracking
+	activeWorkers    int64 // Atomic gauge of workers currently processing a host
+
+	// FoundFileChan optionally receives every found file in real time (see EnableFoundFileChan)
+	// Sends are non-blocking; files are dropped if the consumer falls behind
+	FoundFileChan     chan api.FoundFile
+	droppedFoundFiles int64 // Atomic counter for files dropped on a full channel
+
+	subpathListings int64 // Atomic counter for listings found via root_subpaths
+
+	checksStarted int64 // Atomic counter of file checks, bounded by max_checks
+	checksCapped  int32 // Set to 1 once max_checks was reached
+
+	globalLinks int64 // Atomic counter of found files across all hosts, bounded by max_total_links_global
+	linksCapped int32 // Set to 1 once max_total_links_global was reached
+
+	// Per-host request rate shared with the client (nil = unlimited)
+	rateLimiter *hostRateLimiter
+
+	robotsCache *sync.Map // robots.txt rules per base host, see robotsAllowed
+
+	downloadedFiles int64 // Atomic counter of binaries saved with download_binaries
+	mismatchFiles   int64 // Atomic counter of extension/content-type mismatches
+
+	// Found-file URL rewriting: url_rewrites rules, then the optional transform hook
+	urlRewriter  *filter.URLRewriter
+	urlTransform func(string) string
+
+	// Hostnames in the scan, certificate SANs not among them are crawled as extra vhosts
+	knownHostnames *sync.Map
+	certSANHosts   int64 // Atomic counter of hosts added from certificate SANs
+
+	// Hosts completed by an earlier run are skipped on resume (nil = disabled)
+	scanState    *filter.ScanState
+	resumedHosts int64 // Atomic counter of hosts skipped via the scan state
+
+	// Root listing fingerprint -> first host URL serving it, see skipMirror
+	mirrorFingerprints sync.Map
+	mirrorHosts        int64 // Atomic counter of hosts skipped as mirrors
+
+	// Base host -> *int64 links found, for the scan report (see countLinks)
+	linkTotals sync.Map
+
+	// Confirmed binaries are POSTed to webhook_url in batches (nil = disabled)
+	binaryNotifier *notify.BinaryNotifier
+
+	// Newly blocked hosts are POSTed to blocklist_webhook_url (nil = disabled)
+	blockNotifier *notify.BlockNotifier
+
+	schemeFallbacks int64 // Atomic counter of hosts reached with the other scheme, see alternateSchemeHost
+
+	// Confirmed binaries per base host, bounded by max_binaries_per_host
+	binaryCounters     sync.Map
+	suppressedBinaries int64 // Atomic counter of binaries over the cap
+}
+
+// protocolLister lists files of a non-HTTP host (FTP, SMB)
+type protocolLister interface {
+	ListFiles(ctx context.Context, host api.Host, maxDepth int, maxFiles int) ([]string, error)
+	SetProxy(proxyURL string) error
 }
 
 // ScanStats tracks statistics during scanning
+// The counters are updated atomically so workers never wait on each other for them
 type ScanStats struct {
-	totalHosts       int
-	onlineHosts      int
-	totalFiles       int
-	filteredFiles    int
-	checkedFiles     int
-	binaryFilesFound int
-	writeErrors      int // Count of file write errors
-	mu               sync.Mutex
+	totalHosts       int64
+	onlineHosts      int64
+	totalFiles       int64
+	filteredFiles    int64
+	checkedFiles     int64
+	binaryFilesFound int64
+	writeErrors      int64 // Count of file write errors
+	notListingHosts  int64 // Online hosts whose root is not a directory listing
+
+	// Found files per category (document, archive, executable, ...), guarded by mu
+	categoryCounts map[string]int
+	mu             sync.Mutex
 }
 
 // NewWorker creates a new worker for coordinating crawling
@@ -57,30 +134,117 @@ func NewWorker(
 	queryConfig *config.Query,
 	config *config.Config,
 	maxWorkers int,
+	poster *notify.Poster,
 ) *Worker {
 	// Initialize blocklist
-	blocklist := filter.NewBlocklist(config.BlocklistFile, config.EnableBlocklist, logger)
+	blocklist := newBlocklist(config, logger)
+	var blockNotifier *notify.BlockNotifier
+	if config.BlocklistWebhookURL != "" {
+		blockNotifier = notify.NewBlockNotifier(config.BlocklistWebhookURL, poster, logger)
+	}
 	if err := blocklist.Load(); err != nil {
-		logger.Error("Failed to load blocklist from %s: %v - continuing with empty blocklist (previously blocked hosts may be rescanned)", config.BlocklistFile, err)
+		logger.Error("Failed to load blocklist: %v - continuing with empty blocklist (previously blocked hosts may be rescanned)", err)
+	}
+
+	// Initialize static skip list (operator-maintained pre-scan exclusions)
+	skipList := filter.NewSkipList(logger)
+	if config.SkipHostsFile != "" {
+		if err := skipList.Load(config.SkipHostsFile); err != nil {
+			logger.Error("Failed to load skip hosts file %s: %v - continuing without static exclusions", config.SkipHostsFile, err)
+		}
 	}
 
+	// Normalize cache-busting query parameters and drop non-file schemes in extracted links
+	directoryScanner := scanners.NewDirectoryScanner(logger)
+	if len(config.StripQueryParams) > 0 {
+		directoryScanner.SetStripQueryParams(config.StripQueryParams)
+	}
+	if len(config.AllowedLinkSchemes) > 0 {
+		directoryScanner.SetAllowedLinkSchemes(config.AllowedLinkSchemes)
+	}
+
+	// Tune listing detection for customized or localized index templates
+	if len(config.DirectoryIndicators) > 0 {
+		directoryScanner.SetDirectoryIndicators(config.DirectoryIndicators, config.ReplaceDirectoryIndicators)
+	}
+	directoryScanner.SetListingLinkThreshold(config.ListingLinkThreshold)
+	directoryScanner.SetMaxAnchorsPerPage(config.MaxAnchorsPerPage)
+
+	// Hard ceiling on recursive directory fetches across all hosts
+	directoryScanner.SetMaxConcurrentDirFetches(config.MaxConcurrentDirFetches)
+
+	// Non-HTTP protocols are only listed when explicitly enabled
+	protocolListers := make(map[string]protocolLister)
+	if config.EnableFTP {
+		protocolListers["ftp"] = NewFTPLister(config.HTTPTimeoutSeconds, logger)
+	}
+	if config.EnableSMB {
+		protocolListers["smb"] = NewSMBLister(config.HTTPTimeoutSeconds, logger)
+	}
+	if config.ProxyURL != "" {
+		// Validated with the config, only SOCKS5 proxies are allowed together with FTP or SMB
+		for protocol, lister := range protocolListers {
+			if err := lister.SetProxy(config.ProxyURL); err != nil {
+				logger.Error("WARNING: %v - %s listing disabled", err, strings.ToUpper(protocol))
+				delete(protocolListers, protocol)
+			}
+		}
+	}
+
+	// Rewrite rules are validated with the config, an invalid rule only disables rewriting
+	urlRewriter, err := filter.NewURLRewriter(config.URLRewrites)
+	if err != nil {
+		logger.Error("WARNING: %v - found-file URLs are not rewritten", err)
+	}
+
+	// Optionally alert on confirmed binaries; 0 seconds sends one summary at the end of the scan
+	var binaryNotifier *notify.BinaryNotifier
+	if config.WebhookURL != "" {
+		binaryNotifier = notify.NewBinaryNotifier(config.WebhookURL, time.Duration(config.WebhookIntervalSeconds)*time.Second, poster, logger)
+	}
+
+	// Limit requests per base host; the client shares the limiter for listing fetches
+	rateLimiter := newHostRateLimiter(config.RequestsPerSecondPerHost)
+	client.rateLimiter = rateLimiter
+
 	return &Worker{
 		client:           client,
 		filter:           fileFilter,
 		writer:           writer,
 		logger:           logger,
-		directoryScanner: scanners.NewDirectoryScanner(logger),
+		directoryScanner: directoryScanner,
 		queryConfig:      queryConfig,
 		config:           config,
 		maxWorkers:       maxWorkers,
 		skippedHosts:     &sync.Map{},
 		blockedHosts:     &sync.Map{},
 		skipCounters:     &sync.Map{},
-		stats:            &ScanStats{},
+		stats:            &ScanStats{categoryCounts: make(map[string]int)},
 		blocklist:        blocklist,
+		skipList:         skipList,
+		categorizer:      filter.NewCategorizer(config.FileCategories),
+		protocolListers:  protocolListers,
+		rateLimiter:      rateLimiter,
+		robotsCache:      &sync.Map{},
+		urlRewriter:      urlRewriter,
+		knownHostnames:   &sync.Map{},
+		binaryNotifier:   binaryNotifier,
+		blockNotifier:    blockNotifier,
 	}
 }
 
+// SetURLTransform sets a hook that rewrites or annotates every found-file URL
+// It runs after the url_rewrites rules and before deduplication, filtering and output
+// Returning an empty string drops the file
+func (w *Worker) SetURLTransform(transform func(fileURL string) string) {
+	w.urlTransform = transform
+}
+
+// SetScanState records completed hosts in state and skips hosts it already contains
+func (w *Worker) SetScanState(state *filter.ScanState) {
+	w.scanState = state
+}
+
 // SetFileChecker configures the file checker for the worker
 func (w *Worker) SetFileChecker(checker *filechecker.FileChecker, enabled bool, targetFileName string) {
 	w.fileChecker = checker
@@ -93,14 +257,104 @@ func (w *Worker) SetFileChecker(checker *filechecker.FileChecker, enabled bool,
 	}
 }
 
+// newBlocklist creates the configured blocklist backend
+// Falls back to the file blocklist if the shared Redis store is unreachable
+func newBlocklist(cfg *config.Config, logger *logging.Logger) filter.BlockStore {
+	if cfg.EnableBlocklist && cfg.BlocklistBackend == "redis" {
+		redisBlocklist, err := filter.NewRedisBlocklist(cfg.BlocklistRedisURL, logger)
+		if err == nil {
+			logger.Info("Using shared Redis blocklist")
+			return redisBlocklist
+		}
+		logger.Error("WARNING: Redis blocklist unavailable: %v - falling back to %s", err, cfg.BlocklistFile)
+	}
+
+	return filter.NewBlocklist(cfg.BlocklistFile, cfg.EnableBlocklist, logger)
+}
+
+// alternateSchemeHost returns host with http and https swapped, keeping the port
+// A URL without an explicit port gets the default port of the original scheme
+func alternateSchemeHost(host api.Host) (api.Host, bool) {
+	parsedURL, err := url.Parse(host.URL)
+	if err != nil {
+		return host, false
+	}
+
+	defaultPort := "80"
+	switch parsedURL.Scheme {
+	case "http":
+		parsedURL.Scheme = "https"
+	case "https":
+		parsedURL.Scheme = "http"
+		defaultPort = "443"
+	default:
+		return host, false
+	}
+	if parsedURL.Port() == "" {
+		parsedURL.Host = net.JoinHostPort(parsedURL.Hostname(), defaultPort)
+	}
+
+	host.URL = parsedURL.String()
+	host.Protocol = parsedURL.Scheme
+	return host, true
+}
+
+// redirectedHost returns the host moved to the URL it redirected to
+// The virtual host is dropped when the redirect left the original host
+func redirectedHost(host api.Host, finalURL string) api.Host {
+	originalURL, err1 := url.Parse(host.URL)
+	redirectURL, err2 := url.Parse(finalURL)
+	if err1 == nil && err2 == nil && redirectURL.Hostname() != originalURL.Hostname() {
+		host.VirtualHost = ""
+	}
+	host.URL = finalURL
+	return host
+}
+
+// EnableFoundFileChan creates FoundFileChan with the given buffer size and returns it
+// The channel is closed when ProcessHosts or ProcessHostStream finishes
+func (w *Worker) EnableFoundFileChan(bufferSize int) <-chan api.FoundFile {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	w.FoundFileChan = make(chan api.FoundFile, bufferSize)
+	return w.FoundFileChan
+}
+
+// publishFoundFile sends a found file to FoundFileChan without blocking the crawl
+func (w *Worker) publishFoundFile(fileURL, hostURL string, filtered bool) {
+	if w.FoundFileChan == nil {
+		return
+	}
+
+	foundFile := api.FoundFile{
+		URL:          fileURL,
+		HostURL:      hostURL,
+		RelativePath: strings.TrimPrefix(fileURL, hostURL),
+		Filtered:     filtered,
+	}
+
+	select {
+	case w.FoundFileChan <- foundFile:
+	default:
+		dropped := atomic.AddInt64(&w.droppedFoundFiles, 1)
+		w.logger.Debug("Found file channel full, dropped %s (total dropped: %d)", fileURL, dropped)
+	}
+}
+
 // ProcessHosts crawls each host in parallel
-func (w *Worker) ProcessHosts(hosts []api.Host) {
+// Once ctx is cancelled no new hosts are started and requests in flight are aborted
+func (w *Worker) ProcessHosts(ctx context.Context, hosts []api.Host) {
 	w.logger.Info("Starting to process %d hosts", len(hosts))
-	w.stats.totalHosts = len(hosts)
+	atomic.StoreInt64(&w.stats.totalHosts, int64(len(hosts)))
+
+	// Register all names upfront so SANs of hosts later in the list are not added twice
+	for _, host := range hosts {
+		w.registerHostname(host)
+	}
 
 	// Create channels for parallel processing
 	hostChan := make(chan api.Host, len(hosts))
-	var wg sync.WaitGroup
 
 	// Fill the channel with hosts
 	for _, host := range hosts {
@@ -108,14 +362,56 @@ func (w *Worker) ProcessHosts(hosts []api.Host) {
 	}
 	close(hostChan)
 
+	w.processHostChannel(ctx, hostChan, false)
+}
+
+// ProcessHostStream crawls hosts from a channel as they arrive until it is closed
+// Used to overlap crawling with fetching further Censys result pages
+func (w *Worker) ProcessHostStream(ctx context.Context, hostChan <-chan api.Host) {
+	w.logger.Info("Starting to process streamed hosts")
+	w.processHostChannel(ctx, hostChan, true)
+}
+
+// processHostChannel runs the worker pool on a host channel
+// With countHosts, the total host count grows as hosts are received
+func (w *Worker) processHostChannel(ctx context.Context, hostChan <-chan api.Host, countHosts bool) {
+	var wg sync.WaitGroup
+
 	// Start workers
 	for i := 0; i < w.maxWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
-			for host := range hostChan {
-				w.processHost(host)
+			for {
+				var host api.Host
+				select {
+				case <-ctx.Done():
+					return
+				case next, ok := <-hostChan:
+					if !ok {
+						return
+					}
+					host = next
+				}
+
+				// Both cases may be ready at once, don't start a host after cancellation
+				if ctx.Err() != nil {
+					return
+				}
+
+				if countHosts {
+					atomic.AddInt64(&w.stats.totalHosts, 1)
+					w.registerHostname(host)
+				}
+				atomic.AddInt64(&w.activeWorkers, 1)
+				w.processHost(ctx, host)
+				atomic.AddInt64(&w.activeWorkers, -1)
+
+				// Hosts cut short by a shutdown or the global link cap are not complete and are scanned again on resume
+				if w.scanState != nil && ctx.Err() == nil && !w.LinksCapped() {
+					w.scanState.MarkDone(host.URL)
+				}
 			}
 		}()
 	}
@@ -123,20 +419,60 @@ func (w *Worker) ProcessHosts(hosts []api.Host) {
 	// Wait for all workers to finish
 	wg.Wait()
 
+	if ctx.Err() != nil {
+		w.logger.Info("Stopped dispatching hosts after shutdown request")
+	}
+
+	if sanHosts := atomic.LoadInt64(&w.certSANHosts); sanHosts > 0 {
+		w.logger.Info("Crawled %d additional vhosts found in certificate SANs", sanHosts)
+	}
+	if fallbacks := atomic.LoadInt64(&w.schemeFallbacks); fallbacks > 0 {
+		w.logger.Info("Reached %d hosts only with the other scheme (retry_alternate_scheme)", fallbacks)
+	}
+	if resumed := atomic.LoadInt64(&w.resumedHosts); resumed > 0 {
+		w.logger.Info("Skipped %d hosts already processed by a previous run", resumed)
+	}
+
+	// Send binary findings still waiting for the webhook
+	w.binaryNotifier.Close()
+
 	// Close blocklist (triggers final save and shutdown of save worker)
 	if err := w.blocklist.Close(); err != nil {
 		w.logger.Error("Failed to close blocklist: %v", err)
 	}
+	w.blockNotifier.Close()
+
+	// Signal external consumers that no more files will be sent
+	if w.FoundFileChan != nil {
+		close(w.FoundFileChan)
+		if dropped := atomic.LoadInt64(&w.droppedFoundFiles); dropped > 0 {
+			w.logger.Info("Dropped %d found files because the consumer channel was full", dropped)
+		}
+	}
 
 	w.logger.Info("Finished processing all hosts")
 }
 
 // processHost handles a single host's crawling and scanning
-func (w *Worker) processHost(host api.Host) {
+func (w *Worker) processHost(ctx context.Context, host api.Host) {
 	// Increment processed counter and log progress periodically
 	count := atomic.AddInt64(&w.processedCount, 1)
 	if count%10 == 0 {
-		w.logger.Info("Progress: %d/%d hosts processed", count, w.stats.totalHosts)
+		totalHosts := atomic.LoadInt64(&w.stats.totalHosts)
+		w.logger.Info("Progress: %d/%d hosts processed", count, totalHosts)
+	}
+
+	// Optionally stop starting hosts once the scan-wide link cap was reached
+	if w.config.StopAtGlobalLinkCap && w.LinksCapped() {
+		w.logger.Debug("Skipping host - max_total_links_global reached: %s", host.URL)
+		return
+	}
+
+	// Skip hosts completed before the scan was interrupted (exact URL match)
+	if w.scanState != nil && w.scanState.IsDone(host.URL) {
+		atomic.AddInt64(&w.resumedHosts, 1)
+		w.logger.Debug("Skipping host - already processed by a previous run: %s", host.URL)
+		return
 	}
 
 	// Log the host we're processing - INFO level for user visibility
@@ -145,8 +481,14 @@ func (w *Worker) processHost(host api.Host) {
 	// Extract base host for blocking checks
 	baseHost := w.extractBaseHost(host.URL)
 
+	// Check if host is on the static skip list (by name and by IP)
+	if w.skipList.IsSkipped(baseHost, host.IP) {
+		w.logger.Debug("Skipping host - in skip hosts file: %s", host.URL)
+		return
+	}
+
 	// Check if host is in persistent blocklist
-	if w.blocklist.IsBlocked(baseHost) {
+	if w.blocklist.IsBlocked(ctx, baseHost) {
 		w.logger.Debug("Skipping host - in persistent blocklist: %s", host.URL)
 		return
 	}
@@ -163,29 +505,123 @@ func (w *Worker) processHost(host api.Host) {
 		return
 	}
 
+	// Vhosts from certificate SANs are crawled once this host is finished
+	var sanHosts []api.Host
+	defer func() {
+		for _, sanHost := range sanHosts {
+			if ctx.Err() != nil {
+				return
+			}
+			w.processHost(ctx, sanHost)
+		}
+	}()
+
+	// Discard output held back in binaries-only mode if no binary was found
+	// host is replaced on scheme fallback and redirects, so both URLs are finished
+	originalURL := host.URL
+	defer func() {
+		w.writer.FinishHost(originalURL)
+		if host.URL != originalURL {
+			w.writer.FinishHost(host.URL)
+		}
+	}()
+
+	// FTP and SMB hosts are listed over their own protocol instead of HTTP
+	if host.Protocol == "ftp" || host.Protocol == "smb" {
+		w.processProtocolHost(ctx, host)
+		return
+	}
+
 	// Check if host is online and fetch content
-	online, htmlContent, err := w.client.CheckHostAndFetch(host)
+	result, err := w.client.FetchHost(ctx, host)
 	if err != nil {
 		w.logger.Error("Error checking host %s: %v", host.URL, err)
 		return
 	}
 
-	if !online {
+	// Optionally retry a host that failed to connect with the other scheme once
+	// Services on nonstandard ports are sometimes classified as HTTPS but speak plain HTTP (or vice versa)
+	schemeFallback := ""
+	if w.config.RetryAlternateScheme && !result.Online && result.StatusCode == 0 && ctx.Err() == nil {
+		if altHost, ok := alternateSchemeHost(host); ok {
+			w.logger.Debug("Connection to %s failed, retrying as %s", host.URL, altHost.URL)
+			if altResult, err := w.client.FetchHost(ctx, altHost); err == nil && altResult.StatusCode != 0 {
+				w.logger.Info("Host answered with the other scheme: %s (was %s)", altHost.URL, host.URL)
+				atomic.AddInt64(&w.schemeFallbacks, 1)
+				schemeFallback = host.Protocol
+				host, result = altHost, altResult
+			}
+		}
+	}
+	htmlContent := result.Body
+
+	if !result.Online {
 		w.logger.Debug("Host is offline: %s", host.URL)
 		return
 	}
 
+	// Crawl the final URL if redirects were followed
+	if result.FinalURL != "" && result.FinalURL != host.URL {
+		host = redirectedHost(host, result.FinalURL)
+	}
+
 	// Update stats for online host
-	w.stats.mu.Lock()
-	w.stats.onlineHosts++
-	w.stats.mu.Unlock()
+	atomic.AddInt64(&w.stats.onlineHosts, 1)
 
-	// Host is online, write to output
-	if err := w.writer.WriteRawOutput(host.URL); err != nil {
+	// Host is online, write to output (annotate virtual host if one was sent)
+	hostLine := host.URL
+	if host.VirtualHost != "" {
+		hostLine = fmt.Sprintf("%s (Host: %s)", host.URL, host.VirtualHost)
+	}
+	if schemeFallback != "" {
+		hostLine = fmt.Sprintf("%s (scheme fallback from %s)", hostLine, schemeFallback)
+	}
+	if w.config.VerboseHostOutput {
+		hostLine = fmt.Sprintf("%s  %d  %s", hostLine, result.StatusCode, output.FormatSize(result.Size))
+	}
+	if err := w.writer.WriteRawOutput(hostLine); err != nil {
 		w.logger.Error("Failed to write output for host %s: %v", host.URL, err)
-		w.stats.mu.Lock()
-		w.stats.writeErrors++
-		w.stats.mu.Unlock()
+		atomic.AddInt64(&w.stats.writeErrors, 1)
+	}
+
+	w.writer.RecordReportHost(host.URL)
+
+	// Optional httpx-compatible record for downstream recon tools
+	if w.writer.HTTPXOutputEnabled() {
+		w.writeHTTPXRecord(host, result)
+	}
+
+	// Optional captured response headers for fingerprinting
+	if len(result.Headers) > 0 {
+		if err := w.writer.WriteHeaderRecord(output.HeaderRecord{URL: host.URL, Headers: result.Headers}); err != nil {
+			w.logger.Error("Failed to write header output for host %s: %v", host.URL, err)
+			atomic.AddInt64(&w.stats.writeErrors, 1)
+		}
+	}
+
+	// Optional TLS certificate details for recon (expired, self-signed, interesting SANs)
+	if w.config.CaptureTLSInfo {
+		if record := certificateRecord(host.URL, result.TLS); record != nil {
+			if err := w.writer.WriteCertificateRecord(*record); err != nil {
+				w.logger.Error("Failed to write certificate output for host %s: %v", host.URL, err)
+				atomic.AddInt64(&w.stats.writeErrors, 1)
+			}
+		}
+	}
+
+	// Optionally crawl other names the certificate is valid for on the same IP
+	if w.config.FollowCertSANs {
+		sanHosts = w.certificateSANHosts(host, result.TLS)
+	}
+
+	// Local deduplication map for this host, shared by the root listing and probed subpaths
+	// This map will be garbage collected after this function returns
+	foundUrls := make(map[string]bool)
+
+	// Honor robots.txt before crawling; hosts disallowing their root are not scanned
+	if !w.robotsAllowed(ctx, host) {
+		w.logger.Info("Skipping host disallowed by robots.txt: %s", host.URL)
+		return
 	}
 
 	// Check if this is a targeted check mode
@@ -193,77 +629,232 @@ func (w *Worker) processHost(host api.Host) {
 	foundTargetFile := false
 
 	// Try to check for a specific file if configured
-	if targetedCheckMode {
+	targetHost := api.Host{URL: strings.TrimSuffix(host.URL, "/") + "/" + w.targetFileName, VirtualHost: host.VirtualHost}
+	if targetedCheckMode && w.robotsAllowed(ctx, targetHost) && w.reserveCheck() {
 		w.logger.Debug("Checking for specific file %s at %s", w.targetFileName, host.URL)
 
-		found, contentType, err := w.fileChecker.CheckSpecificFile(host.URL, w.targetFileName)
-		if err == nil && found {
+		w.rateLimiter.wait(ctx, host.URL)
+		found, contentType, err := w.fileChecker.CheckSpecificFile(ctx, host.URL, w.targetFileName)
+		binaryURL := fmt.Sprintf("%s/%s", host.URL, w.targetFileName)
+		if err == nil && found && !w.reserveBinary(binaryURL) {
+			// Hosts over max_binaries_per_host only add to the suppressed tally
+			w.logger.Debug("Binary not recorded - host reached max_binaries_per_host: %s", binaryURL)
+			w.writer.RecordSuppressedBinary(binaryURL)
+			atomic.AddInt64(&w.stats.checkedFiles, 1)
+			foundTargetFile = true
+		} else if err == nil && found {
 			w.logger.Info("Found binary file '%s' at %s with Content-Type: %s",
 				w.targetFileName, host.URL, contentType)
-			binaryURL := fmt.Sprintf("%s/%s", host.URL, w.targetFileName)
 
 			// Write to raw output
 			if err := w.writer.WriteRawOutput(fmt.Sprintf("Found binary file: %s with Content-Type: %s", binaryURL, contentType)); err != nil {
 				w.logger.Error("Failed to write raw output for binary file %s: %v", binaryURL, err)
-				w.stats.mu.Lock()
-				w.stats.writeErrors++
-				w.stats.mu.Unlock()
+				atomic.AddInt64(&w.stats.writeErrors, 1)
 			}
 
 			// Write to binary output
 			binaryLine := fmt.Sprintf("%s with Content-Type: %s", binaryURL, contentType)
 			if err := w.writer.WriteBinaryOutput(binaryLine); err != nil {
 				w.logger.Error("Failed to write binary output for %s: %v", binaryURL, err)
-				w.stats.mu.Lock()
-				w.stats.writeErrors++
-				w.stats.mu.Unlock()
+				atomic.AddInt64(&w.stats.writeErrors, 1)
 			}
 
 			// Update check statistics
-			w.stats.mu.Lock()
-			w.stats.checkedFiles++
-			w.stats.binaryFilesFound++
-			w.stats.mu.Unlock()
+			atomic.AddInt64(&w.stats.checkedFiles, 1)
+			atomic.AddInt64(&w.stats.binaryFilesFound, 1)
+
+			w.downloadBinary(ctx, binaryURL, contentType)
+			w.notifyBinary(binaryURL, contentType, host.URL)
 
 			// Mark that we found the target file for this host
 			foundTargetFile = true
 		} else if err != nil {
 			w.logger.Debug("Failed to check for specific file: %v", err)
+			w.reportMismatch(binaryURL, contentType)
 		}
 	}
 
 	// Process directory content if not in targeted mode or if target file was not found
+	// Mirrors of an already scanned listing are not probed for subpaths either
 	if !targetedCheckMode || !foundTargetFile {
-		w.processDirectoryContent(host, htmlContent)
+		// Skip parsing for roots that are obviously not HTML (APIs, media); JSON may be a listing
+		if w.config.RequireHTML && !isHTMLContentType(result.ContentType) && !scanners.IsJSONContentType(result.ContentType) {
+			w.logger.Debug("Host root is not HTML (Content-Type: %s), not a listing: %s", result.ContentType, host.URL)
+			atomic.AddInt64(&w.stats.notListingHosts, 1)
+		} else {
+			if mirror := w.processDirectoryContent(ctx, host, htmlContent, result.ContentType, foundUrls); mirror {
+				return
+			}
+		}
+	}
+
+	// Probe common listing roots below the document root
+	if len(w.config.RootSubpaths) > 0 {
+		w.probeRootSubpaths(ctx, host, foundUrls)
+	}
+}
+
+// isCatchAllServer requests a random nonexistent directory below the listing
+// Real directory servers return 404, catch-all sites return 200 for every path
+func (w *Worker) isCatchAllServer(ctx context.Context, host api.Host) bool {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return false
+	}
+
+	probeHost := host
+	probeHost.URL = strings.TrimSuffix(host.URL, "/") + "/censei-" + hex.EncodeToString(suffix) + "/"
+
+	result, err := w.client.FetchHost(ctx, probeHost)
+	if err != nil {
+		return false
+	}
+	return result.Online
+}
+
+// probeRootSubpaths fetches the configured subpaths of a host and scans those serving a listing
+// Subpaths already discovered while crawling the root listing are skipped
+func (w *Worker) probeRootSubpaths(ctx context.Context, host api.Host, foundUrls map[string]bool) {
+	for _, subpath := range w.config.RootSubpaths {
+		subpath = strings.Trim(subpath, "/")
+		if subpath == "" {
+			continue
+		}
+
+		subHost := host
+		subHost.URL = host.URL + "/" + subpath + "/"
+		if foundUrls[subHost.URL] {
+			w.logger.Debug("Skipping subpath already found while crawling: %s", subHost.URL)
+			continue
+		}
+
+		// Stop probing once the host got blocked (e.g. by link limits)
+		if _, isBlocked := w.blockedHosts.Load(w.extractBaseHost(host.URL)); isBlocked {
+			return
+		}
+
+		if !w.robotsAllowed(ctx, subHost) {
+			w.logger.Debug("Skipping subpath disallowed by robots.txt: %s", subHost.URL)
+			continue
+		}
+
+		result, err := w.client.FetchHost(ctx, subHost)
+		if err != nil || !result.Online || result.StatusCode != http.StatusOK {
+			continue
+		}
+		if isListing, _ := w.directoryScanner.DetectDirectoryListing(result.Body, result.ContentType); !isListing {
+			continue
+		}
+
+		w.logger.Info("Found listing via subpath probing: %s", subHost.URL)
+		atomic.AddInt64(&w.subpathListings, 1)
+		foundUrls[subHost.URL] = true
+		if err := w.writer.WriteRawOutput("Subpath listing: " + subHost.URL); err != nil {
+			w.logger.Error("Failed to write raw output for subpath listing %s: %v", subHost.URL, err)
+			atomic.AddInt64(&w.stats.writeErrors, 1)
+		}
+
+		w.scanListing(ctx, subHost, result.Body, result.ContentType, foundUrls)
+	}
+}
+
+// writeHTTPXRecord writes an online host to the httpx JSON Lines output
+func (w *Worker) writeHTTPXRecord(host api.Host, result *FetchResult) {
+	port := strconv.Itoa(host.Port)
+	if parsedURL, err := url.Parse(host.URL); err == nil && parsedURL.Port() == "" && host.Port == 0 {
+		port = "80"
+		if parsedURL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	address := host.IP
+	if address == "" {
+		address = host.BaseAddress
+	}
+
+	record := output.NewHTTPXRecord(host.URL, host.URL, address, port, host.Protocol)
+	record.StatusCode = result.StatusCode
+	record.Title = output.ExtractTitle(result.Body)
+	record.ContentLength = result.Size
+	record.ContentType = result.ContentType
+	record.Webserver = result.Server
+
+	if err := w.writer.WriteHTTPXRecord(record); err != nil {
+		w.logger.Error("Failed to write httpx output for host %s: %v", host.URL, err)
+		atomic.AddInt64(&w.stats.writeErrors, 1)
 	}
 }
 
 // processDirectoryContent handles directory listing scanning and file processing
-func (w *Worker) processDirectoryContent(host api.Host, htmlContent string) {
+// contentType selects between JSON and HTML listings
+// Returns true if the listing was skipped as a mirror of another host (skip_mirror_hosts)
+func (w *Worker) processDirectoryContent(ctx context.Context, host api.Host, htmlContent string, contentType string, foundUrls map[string]bool) bool {
 	// Extract base host and check if blocked
 	baseHost := w.extractBaseHost(host.URL)
 
 	// Early check for blocked host
-	if w.blocklist.IsBlocked(baseHost) {
+	if w.blocklist.IsBlocked(ctx, baseHost) {
 		w.logger.Debug("Skipping directory processing - host in blocklist: %s", host.URL)
-		return
+		return false
 	}
 
 	if _, isBlocked := w.blockedHosts.Load(baseHost); isBlocked {
 		w.logger.Debug("Skipping directory processing - host blocked: %s", host.URL)
-		return
+		return false
+	}
+
+	// JSON listings carry their entries directly, the HTML heuristics below do not apply
+	isJSON := scanners.IsJSONContentType(contentType)
+
+	// File browsers that render the listing via JavaScript have no links in the initial HTML
+	if !isJSON {
+		if jsListing := w.directoryScanner.DetectJSListing(htmlContent); jsListing != "" {
+			if w.processJSListing(ctx, host, htmlContent, jsListing, foundUrls) {
+				return false
+			}
+		}
+	}
+
+	// Tiny responses (placeholders, redirect stubs) are not listings unless they carry the "Index of" marker
+	if !isJSON && w.config.MinListingBytes > 0 && len(htmlContent) < w.config.MinListingBytes &&
+		!w.directoryScanner.HasIndexOfMarker(htmlContent) {
+		w.logger.Debug("Host content too small for a listing (%d < %d bytes): %s", len(htmlContent), w.config.MinListingBytes, host.URL)
+		atomic.AddInt64(&w.stats.notListingHosts, 1)
+		return false
 	}
 
 	// Check if content is a directory listing
-	if !w.directoryScanner.IsDirectoryListing(htmlContent) {
-		w.logger.Debug("Host content is not a directory listing: %s", host.URL)
-		return
+	isListing, reason := w.directoryScanner.DetectDirectoryListing(htmlContent, contentType)
+	if w.config.LogDetectionReason {
+		verdict := "Listing detected"
+		if !isListing {
+			verdict = "Not a listing"
+		}
+		if err := w.writer.WriteRawOutput(fmt.Sprintf("%s: %s (%s)", verdict, host.URL, reason)); err != nil {
+			w.logger.Error("Failed to write detection reason for %s: %v", host.URL, err)
+			atomic.AddInt64(&w.stats.writeErrors, 1)
+		}
 	}
+	if !isListing {
+		w.logger.Debug("Host content is not a directory listing: %s (%s)", host.URL, reason)
+		atomic.AddInt64(&w.stats.notListingHosts, 1)
+		return false
+	}
+	w.logger.Debug("Host content is a directory listing: %s (%s)", host.URL, reason)
 
-	// Create local deduplication map for this host
-	// This map will be garbage collected after this function returns
-	foundUrls := make(map[string]bool)
+	// Optionally skip hosts serving the same root listing as a host already scanned
+	if w.config.SkipMirrorHosts && w.skipMirror(host, htmlContent, contentType) {
+		return true
+	}
+
+	w.scanListing(ctx, host, htmlContent, contentType, foundUrls)
+	return false
+}
 
+// scanListing extracts files from a confirmed directory listing (recursively if configured)
+// foundUrls deduplicates files across all listings of the same host
+func (w *Worker) scanListing(ctx context.Context, host api.Host, htmlContent string, contentType string, foundUrls map[string]bool) {
 	var fileURLs []string
 
 	// Check if recursive scanning is enabled
@@ -285,121 +876,572 @@ func (w *Worker) processDirectoryContent(host api.Host, htmlContent string) {
 		if w.config.MaxSkipsBeforeBlock > 0 && newSkipCount >= int64(w.config.MaxSkipsBeforeBlock) {
 			w.logger.Info("Blocking entire base host after %d skips: %s", newSkipCount, baseHost)
 			w.blockedHosts.Store(baseHost, true)
-			w.blocklist.AddHost(baseHost)
+			if w.blocklist.AddHost(baseHost) {
+				// Share the block with other scanners (queued, never blocks scanning)
+				w.blockNotifier.Notify(notify.BlockEvent{Host: baseHost, BlockedAt: time.Now()})
+			}
 
 			// Mark the original host URL as skipped (only after blocking threshold is reached)
 			w.skippedHosts.Store(host.URL, true)
 		}
 	}
 
+	// Catch-all servers answer every path with 200 and would be recursed endlessly
+	if recursive && maxDepth > 1 && w.config.VerifyListingServer && w.isCatchAllServer(ctx, host) {
+		w.logger.Info("Server answers nonexistent paths with 200, not recursing: %s", host.URL)
+		recursive = false
+	}
+
+	var directoryURLs []string
 	if recursive && maxDepth > 1 {
+		// Directories disallowed by robots.txt are not fetched
+		var client scanners.HTTPClient = w.client
+		if w.config.RespectRobots {
+			client = robotsClient{worker: w}
+		}
+
 		w.logger.Info("Starting recursive scan with max-depth %d for %s", maxDepth, host.URL)
-		fileURLs = w.directoryScanner.ScanHostRecursive(host, htmlContent, maxDepth, w.client, w.config, skipCallback)
+		fileURLs, directoryURLs = w.directoryScanner.ScanHostRecursive(ctx, host, htmlContent, contentType, maxDepth, client, w.config, skipCallback)
 	} else {
 		w.logger.Info("Scanning directory listing: %s", host.URL)
-		fileURLs = w.directoryScanner.ScanHost(host, htmlContent)
+		fileURLs = w.directoryScanner.ScanHost(host, htmlContent, contentType)
+		if w.config.ExportDirectories {
+			directoryURLs = w.directoryScanner.FilterDirectories(fileURLs)
+		}
+	}
+
+	// Export discovered directories for a site map
+	if w.config.ExportDirectories {
+		for _, directoryURL := range directoryURLs {
+			if err := w.writer.WriteDirectoryOutput(directoryURL); err != nil {
+				w.logger.Error("Failed to write directory output for %s: %v", directoryURL, err)
+				atomic.AddInt64(&w.stats.writeErrors, 1)
+			}
+		}
 	}
 
 	// Log found files for user visibility
 	if len(fileURLs) > 0 {
 		w.logger.Info("Found %d files at %s", len(fileURLs), host.URL)
 	}
+	w.countLinks(host.URL, len(fileURLs))
 
 	// Process each found file with local deduplication map
 	for _, fileURL := range fileURLs {
-		w.processFoundFile(fileURL, foundUrls)
+		w.processFoundFile(ctx, fileURL, host.URL, foundUrls)
 	}
 }
 
+// processJSListing handles hosts whose listing may be rendered client-side by a file browser
+// Listings with a known JSON API are fetched, all others are flagged for manual review
+// Returns false if the HTML contains links after all and should be scanned normally
+// foundUrls deduplicates files across all listings of the same host
+func (w *Worker) processJSListing(ctx context.Context, host api.Host, htmlContent string, jsListing string, foundUrls map[string]bool) bool {
+	if jsListing == scanners.JSListingH5ai {
+		fileURLs, err := w.directoryScanner.ScanH5ai(ctx, host, w.client)
+		if err == nil {
+			w.logger.Info("Found %d files via %s API at %s", len(fileURLs), jsListing, host.URL)
+			w.countLinks(host.URL, len(fileURLs))
+			for _, fileURL := range fileURLs {
+				w.processFoundFile(ctx, fileURL, host.URL, foundUrls)
+			}
+			return true
+		}
+		w.logger.Debug("Failed to fetch %s listing for %s: %v", jsListing, host.URL, err)
+	}
+
+	// Some of these browsers render server-side, so only flag hosts without links
+	if len(w.directoryScanner.ScanHost(host, htmlContent, "")) > 0 {
+		return false
+	}
+
+	w.logger.Info("JS listing (not parsed) at %s: %s", host.URL, jsListing)
+	if err := w.writer.WriteRawOutput(fmt.Sprintf("JS listing (not parsed): %s (%s)", host.URL, jsListing)); err != nil {
+		w.logger.Error("Failed to write raw output for JS listing %s: %v", host.URL, err)
+		atomic.AddInt64(&w.stats.writeErrors, 1)
+	}
+	return true
+}
+
 // processFoundFile handles individual file processing including filtering and checking
-func (w *Worker) processFoundFile(fileURL string, foundUrls map[string]bool) {
+func (w *Worker) processFoundFile(ctx context.Context, fileURL, hostURL string, foundUrls map[string]bool) {
+	// Rewrite the URL first so dedup, filters and all outputs see the final URL
+	fileURL = w.rewriteURL(fileURL)
+	if fileURL == "" {
+		return
+	}
+
+	if !w.reserveFoundFile(fileURL, foundUrls) {
+		return
+	}
+
+	// Write to raw output
+	if err := w.writer.WriteRawOutput("Found file: " + fileURL); err != nil {
+		w.logger.Error("Failed to write raw output for file %s: %v", fileURL, err)
+		atomic.AddInt64(&w.stats.writeErrors, 1)
+	}
+	w.recordCategory(fileURL, fileExtension(fileURL))
+
+	// Apply filters, then the optional size range
+	filtered := w.filter.ShouldFilter(fileURL) && w.sizeInRange(ctx, fileURL)
+	w.recordFiltered(fileURL, hostURL, filtered)
+
+	// Enumerate files packaged in ZIP archives without downloading them
+	if w.config.ListArchiveContents && scanners.IsListableArchive(fileURL) && w.robotsAllowed(ctx, api.Host{URL: fileURL}) {
+		w.listArchiveContents(ctx, fileURL, hostURL, foundUrls)
+	}
+
+	// Check file content type of filtered files if enabled
+	// No new checks are started once the scan is cancelled
+	if filtered && w.checkEnabled && w.fileChecker != nil && ctx.Err() == nil && strings.HasPrefix(fileURL, "http") && w.fileChecker.ShouldCheck(fileURL) && w.robotsAllowed(ctx, api.Host{URL: fileURL}) && w.reserveCheck() {
+		w.checkFileContent(ctx, fileURL, hostURL)
+	}
+}
+
+// reserveFoundFile deduplicates a file for its host and counts it against max_total_links_global
+// Returns false if the file was already found or the scan-wide cap was reached
+func (w *Worker) reserveFoundFile(fileURL string, foundUrls map[string]bool) bool {
 	// Check if we've already found this URL (local deduplication for this host)
 	if foundUrls[fileURL] {
 		w.logger.Debug("Skipping duplicate URL: %s", fileURL)
-		return
+		return false
 	}
 	foundUrls[fileURL] = true
 
+	// Stop recording files once the scan-wide cap was reached
+	if !w.reserveLink() {
+		return false
+	}
+
 	// Update stats for file found
+	atomic.AddInt64(&w.stats.totalFiles, 1)
+	return true
+}
+
+// recordCategory counts the extension for the optional inventory and tallies the file category
+func (w *Worker) recordCategory(fileURL, extension string) {
+	w.writer.RecordExtension(extension)
+	category := w.categorizer.Category(extension)
 	w.stats.mu.Lock()
-	w.stats.totalFiles++
+	w.stats.categoryCounts[category]++
 	w.stats.mu.Unlock()
+	if err := w.writer.WriteCategoryOutput(category, fileURL); err != nil {
+		w.logger.Error("Failed to write category output for %s: %v", fileURL, err)
+		atomic.AddInt64(&w.stats.writeErrors, 1)
+	}
+}
 
-	// Write to raw output
-	if err := w.writer.WriteRawOutput("Found file: " + fileURL); err != nil {
-		w.logger.Error("Failed to write raw output for file %s: %v", fileURL, err)
-		w.stats.mu.Lock()
-		w.stats.writeErrors++
-		w.stats.mu.Unlock()
+// recordFiltered publishes a found file and writes it to the filtered output if it matched the filters
+func (w *Worker) recordFiltered(fileURL, hostURL string, filtered bool) {
+	w.publishFoundFile(fileURL, hostURL, filtered)
+	w.writer.RecordReportFile(hostURL, fileURL, filtered)
+	if !filtered {
+		return
+	}
+
+	w.logger.Debug("File matched filter: %s", fileURL)
+
+	// Update stats for filtered file
+	atomic.AddInt64(&w.stats.filteredFiles, 1)
+
+	// Write to filtered output
+	if err := w.writer.WriteFilteredOutput(fileURL); err != nil {
+		w.logger.Error("Failed to write filtered output for %s: %v", fileURL, err)
+		atomic.AddInt64(&w.stats.writeErrors, 1)
+	}
+}
+
+// rewriteURL applies the url_rewrites rules and the transform hook to a found-file URL
+func (w *Worker) rewriteURL(fileURL string) string {
+	rewritten := fileURL
+	if w.urlRewriter != nil {
+		rewritten = w.urlRewriter.Rewrite(rewritten)
+	}
+	if w.urlTransform != nil {
+		rewritten = w.urlTransform(rewritten)
+	}
+
+	if rewritten != fileURL {
+		w.logger.Debug("Rewrote found URL %s to %s", fileURL, rewritten)
+	}
+	return rewritten
+}
+
+// processProtocolHost lists an FTP or SMB server and reports its files like HTTP findings
+func (w *Worker) processProtocolHost(ctx context.Context, host api.Host) {
+	lister, ok := w.protocolListers[host.Protocol]
+	if !ok {
+		w.logger.Debug("Skipping %s host - protocol not enabled: %s", host.Protocol, host.URL)
+		return
+	}
+
+	maxDepth := 1
+	if w.queryConfig.Recursive == "yes" && w.queryConfig.MaxDepth > 1 {
+		maxDepth = w.queryConfig.MaxDepth
+	}
+
+	fileURLs, err := lister.ListFiles(ctx, host, maxDepth, w.config.MaxTotalLinks)
+	if err != nil {
+		w.logger.Debug("%s host not accessible: %s (%v)", strings.ToUpper(host.Protocol), host.URL, err)
+		return
+	}
+
+	atomic.AddInt64(&w.stats.onlineHosts, 1)
+
+	if err := w.writer.WriteRawOutput(host.URL); err != nil {
+		w.logger.Error("Failed to write output for host %s: %v", host.URL, err)
+		atomic.AddInt64(&w.stats.writeErrors, 1)
+	}
+	w.writer.RecordReportHost(host.URL)
+
+	w.logger.Info("Found %d files on %s host %s", len(fileURLs), strings.ToUpper(host.Protocol), host.URL)
+	foundUrls := make(map[string]bool, len(fileURLs))
+	for _, fileURL := range fileURLs {
+		w.processFoundFile(ctx, fileURL, host.URL, foundUrls)
 	}
+}
 
-	// Apply filters
-	if w.filter.ShouldFilter(fileURL) {
-		w.logger.Debug("File matched filter: %s", fileURL)
+// listArchiveContents records the files inside a remote ZIP archive like files of the listing
+// Entries are reported as "archiveURL#path", deduplicated and counted against the host's limits,
+// and filtered by name and packaged size; they are not checked as they cannot be fetched on their own
+func (w *Worker) listArchiveContents(ctx context.Context, archiveURL, hostURL string, foundUrls map[string]bool) {
+	entries, err := w.directoryScanner.ListZipContents(ctx, archiveURL, w.client)
+	if err != nil {
+		w.logger.Debug("Failed to list archive contents of %s: %v", archiveURL, err)
+		return
+	}
+
+	w.logger.Info("Archive %s contains %d files", archiveURL, len(entries))
+	w.countLinks(hostURL, len(entries))
+	for _, entry := range entries {
+		// Entries share max_total_links with the files of the listing
+		if w.config.MaxTotalLinks > 0 && len(foundUrls) >= w.config.MaxTotalLinks {
+			w.logger.Info("Host reached max_total_links (%d), remaining entries of %s are not recorded", w.config.MaxTotalLinks, archiveURL)
+			return
+		}
+
+		entryURL := archiveURL + "#" + entry.Name
+		if !w.reserveFoundFile(entryURL, foundUrls) {
+			continue
+		}
+
+		if err := w.writer.WriteRawOutput(fmt.Sprintf("Archive entry: %s (%s)", entryURL, output.FormatSize(int(entry.Size)))); err != nil {
+			w.logger.Error("Failed to write raw output for archive entry %s: %v", entryURL, err)
+			atomic.AddInt64(&w.stats.writeErrors, 1)
+		}
+		w.recordCategory(entryURL, fileExtension("/"+entry.Name))
+
+		filtered := w.filter.ShouldFilter(entry.Name) && w.sizeAllowed(entryURL, int64(entry.Size))
+		w.recordFiltered(entryURL, hostURL, filtered)
+	}
+}
 
-		// Update stats for filtered file
-		w.stats.mu.Lock()
-		w.stats.filteredFiles++
-		w.stats.mu.Unlock()
+// sizeInRange checks a file against min_file_size and max_file_size using a HEAD request
+// Files of unknown size pass unless drop_unknown_size is set
+func (w *Worker) sizeInRange(ctx context.Context, fileURL string) bool {
+	minSize, maxSize := w.config.MinFileSize, w.config.MaxFileSize
+	if minSize <= 0 && maxSize <= 0 {
+		return true
+	}
 
-		// Write to filtered output
-		if err := w.writer.WriteFilteredOutput(fileURL); err != nil {
-			w.logger.Error("Failed to write filtered output for %s: %v", fileURL, err)
-			w.stats.mu.Lock()
-			w.stats.writeErrors++
-			w.stats.mu.Unlock()
+	// FTP/SMB files and files the HEAD request may not be sent for are of unknown size
+	size := int64(-1)
+	if w.fileChecker != nil && strings.HasPrefix(fileURL, "http") && w.robotsAllowed(ctx, api.Host{URL: fileURL}) && w.rateLimiter.wait(ctx, fileURL) == nil {
+		var err error
+		size, err = w.fileChecker.FileSize(ctx, fileURL)
+		if err != nil {
+			w.logger.Debug("Could not read size of %s: %v", fileURL, err)
 		}
+	}
+
+	return w.sizeAllowed(fileURL, size)
+}
+
+// sizeAllowed checks a known file size (-1 = unknown) against min_file_size and max_file_size
+func (w *Worker) sizeAllowed(fileURL string, size int64) bool {
+	minSize, maxSize := w.config.MinFileSize, w.config.MaxFileSize
+	if minSize <= 0 && maxSize <= 0 {
+		return true
+	}
 
-		// Check file content type if enabled
-		if w.checkEnabled && w.fileChecker != nil && w.fileChecker.ShouldCheck(fileURL) {
-			w.checkFileContent(fileURL)
+	if size < 0 {
+		if w.config.DropUnknownSize {
+			w.logger.Debug("Dropping file of unknown size: %s", fileURL)
+			return false
 		}
+		return true
+	}
+
+	if (minSize > 0 && size < minSize) || (maxSize > 0 && size > maxSize) {
+		w.logger.Debug("Dropping file outside size range: %s (%d bytes)", fileURL, size)
+		return false
 	}
+	return true
 }
 
 // checkFileContent verifies if a file contains binary content
-func (w *Worker) checkFileContent(fileURL string) {
+func (w *Worker) checkFileContent(ctx context.Context, fileURL, hostURL string) {
 	// Increment checked files counter (only once per check)
-	w.stats.mu.Lock()
-	w.stats.checkedFiles++
-	w.stats.mu.Unlock()
+	atomic.AddInt64(&w.stats.checkedFiles, 1)
+
+	if err := w.rateLimiter.wait(ctx, fileURL); err != nil {
+		return
+	}
 
-	found, contentType, err := w.fileChecker.CheckFileURL(fileURL)
+	found, contentType, err := w.fileChecker.CheckFileURL(ctx, fileURL)
 	if err == nil && found {
+		// Hosts over max_binaries_per_host only add to the suppressed tally
+		if !w.reserveBinary(fileURL) {
+			w.logger.Debug("Binary not recorded - host reached max_binaries_per_host: %s", fileURL)
+			w.writer.RecordSuppressedBinary(fileURL)
+			return
+		}
+
 		w.logger.Info("Found binary file at %s with Content-Type: %s", fileURL, contentType)
 
 		// Write to raw output
 		if err := w.writer.WriteRawOutput(fmt.Sprintf("Found binary file: %s with Content-Type: %s", fileURL, contentType)); err != nil {
 			w.logger.Error("Failed to write raw output for binary file %s: %v", fileURL, err)
-			w.stats.mu.Lock()
-			w.stats.writeErrors++
-			w.stats.mu.Unlock()
+			atomic.AddInt64(&w.stats.writeErrors, 1)
 		}
 
 		// Write to binary output
 		binaryLine := fmt.Sprintf("%s with Content-Type: %s", fileURL, contentType)
 		if err := w.writer.WriteBinaryOutput(binaryLine); err != nil {
 			w.logger.Error("Failed to write binary output for %s: %v", fileURL, err)
-			w.stats.mu.Lock()
-			w.stats.writeErrors++
-			w.stats.mu.Unlock()
+			atomic.AddInt64(&w.stats.writeErrors, 1)
 		}
 
 		// Update binary files found statistic
-		w.stats.mu.Lock()
-		w.stats.binaryFilesFound++
-		w.stats.mu.Unlock()
+		atomic.AddInt64(&w.stats.binaryFilesFound, 1)
+
+		w.downloadBinary(ctx, fileURL, contentType)
+		w.notifyBinary(fileURL, contentType, hostURL)
 	} else if err != nil {
 		w.logger.Debug("File check failed for %s: %v", fileURL, err)
+		w.reportMismatch(fileURL, contentType)
+	}
+}
+
+// notifyBinary queues a confirmed binary for the webhook (does nothing without webhook_url)
+func (w *Worker) notifyBinary(fileURL, contentType, hostURL string) {
+	w.binaryNotifier.Notify(notify.BinaryFinding{
+		URL:         fileURL,
+		ContentType: contentType,
+		Host:        hostURL,
+		FoundAt:     time.Now(),
+	})
+}
+
+// reportMismatch records a checked file whose extension promises a binary (executable or
+// archive category) but which was served with a non-binary Content-Type, e.g. a .exe served
+// as text/html. Such files may be disguised downloads or error pages answering with 200.
+// Does nothing unless report_mismatches is enabled
+func (w *Worker) reportMismatch(fileURL string, contentType string) {
+	if !w.config.ReportMismatches || contentType == "" || filechecker.IsBinaryContentType(contentType) {
+		return
+	}
+
+	extension := fileExtension(fileURL)
+	category := w.categorizer.Category(extension)
+	if category != "executable" && category != "archive" {
+		return
+	}
+
+	w.logger.Info("Extension/content-type mismatch: %s (%s) served as %s", fileURL, extension, contentType)
+	atomic.AddInt64(&w.mismatchFiles, 1)
+
+	if err := w.writer.WriteMismatchOutput(fmt.Sprintf("%s (%s) served as Content-Type: %s", fileURL, extension, contentType)); err != nil {
+		w.logger.Error("Failed to write mismatch output for %s: %v", fileURL, err)
+		atomic.AddInt64(&w.stats.writeErrors, 1)
+	}
+}
+
+// downloadBinary saves a confirmed binary and records it in the download manifest
+// Does nothing unless download_binaries is enabled
+func (w *Worker) downloadBinary(ctx context.Context, fileURL string, contentType string) {
+	if !w.config.DownloadBinaries || ctx.Err() != nil {
+		return
+	}
+
+	destPath := w.writer.ReserveDownloadPath(fileURL)
+	if destPath == "" {
+		return
+	}
+
+	if err := w.rateLimiter.wait(ctx, fileURL); err != nil {
+		return
+	}
+
+	result, err := w.fileChecker.DownloadFile(ctx, fileURL, destPath, w.config.MaxDownloadSize)
+	if err != nil {
+		w.logger.Info("Skipped download of %s: %v", fileURL, err)
+		return
+	}
+
+	switch {
+	case result.Existing:
+		w.logger.Info("Already downloaded %s to %s", fileURL, destPath)
+	case result.ResumedFrom > 0:
+		w.logger.Info("Downloaded %s to %s (%s, resumed at %s)", fileURL, destPath,
+			output.FormatSize(int(result.Size)), output.FormatSize(int(result.ResumedFrom)))
+	default:
+		w.logger.Info("Downloaded %s to %s (%s)", fileURL, destPath, output.FormatSize(int(result.Size)))
+	}
+	atomic.AddInt64(&w.downloadedFiles, 1)
+
+	record := output.DownloadRecord{
+		URL:          fileURL,
+		Path:         destPath,
+		Size:         result.Size,
+		SHA256:       result.SHA256,
+		ResumedFrom:  result.ResumedFrom,
+		ContentType:  contentType,
+		DownloadedAt: time.Now(),
+	}
+	if err := w.writer.WriteDownloadRecord(record); err != nil {
+		w.logger.Error("Failed to write download record for %s: %v", fileURL, err)
+		atomic.AddInt64(&w.stats.writeErrors, 1)
 	}
 }
 
 // GetStats returns the current scan statistics
-func (w *Worker) GetStats() (int, int, int, int, int, int, int) {
+func (w *Worker) GetStats() (int, int, int, int, int, int, int, int) {
+	load := func(counter *int64) int {
+		return int(atomic.LoadInt64(counter))
+	}
+	return load(&w.stats.totalHosts), load(&w.stats.onlineHosts), load(&w.stats.totalFiles),
+		load(&w.stats.filteredFiles), load(&w.stats.checkedFiles), load(&w.stats.binaryFilesFound), load(&w.stats.writeErrors),
+		load(&w.stats.notListingHosts)
+}
+
+// GetProcessedHosts returns the number of hosts taken from the queue so far, including skipped ones
+func (w *Worker) GetProcessedHosts() int {
+	return int(atomic.LoadInt64(&w.processedCount))
+}
+
+// GetActiveWorkers returns the number of workers currently processing a host
+func (w *Worker) GetActiveWorkers() int {
+	return int(atomic.LoadInt64(&w.activeWorkers))
+}
+
+// GetCategoryCounts returns the number of found files per category
+func (w *Worker) GetCategoryCounts() map[string]int {
 	w.stats.mu.Lock()
 	defer w.stats.mu.Unlock()
-	return w.stats.totalHosts, w.stats.onlineHosts, w.stats.totalFiles,
-		w.stats.filteredFiles, w.stats.checkedFiles, w.stats.binaryFilesFound, w.stats.writeErrors
+
+	counts := make(map[string]int, len(w.stats.categoryCounts))
+	for category, count := range w.stats.categoryCounts {
+		counts[category] = count
+	}
+	return counts
+}
+
+// reserveCheck counts a file check against max_checks
+// Returns false once the limit is reached; files are then still recorded but not checked
+func (w *Worker) reserveCheck() bool {
+	if w.config.MaxChecks <= 0 {
+		return true
+	}
+	if atomic.AddInt64(&w.checksStarted, 1) > int64(w.config.MaxChecks) {
+		if atomic.CompareAndSwapInt32(&w.checksCapped, 0, 1) {
+			w.logger.Info("Reached max_checks (%d), remaining files are not checked", w.config.MaxChecks)
+		}
+		return false
+	}
+	return true
+}
+
+// reserveLink counts a found file against max_total_links_global
+// Returns false once the cap was reached
+func (w *Worker) reserveLink() bool {
+	if w.config.MaxTotalLinksGlobal <= 0 {
+		return true
+	}
+	if atomic.AddInt64(&w.globalLinks, 1) > int64(w.config.MaxTotalLinksGlobal) {
+		if atomic.CompareAndSwapInt32(&w.linksCapped, 0, 1) {
+			if w.config.StopAtGlobalLinkCap {
+				w.logger.Error("WARNING: Reached max_total_links_global (%d) - no further files are recorded and no new hosts are started, output is truncated", w.config.MaxTotalLinksGlobal)
+			} else {
+				w.logger.Error("WARNING: Reached max_total_links_global (%d) - no further files are recorded, output is truncated", w.config.MaxTotalLinksGlobal)
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// reserveBinary counts a confirmed binary against max_binaries_per_host of its base host
+// Returns false once the host reached the cap; the binary then counts as suppressed
+func (w *Worker) reserveBinary(fileURL string) bool {
+	if w.config.MaxBinariesPerHost <= 0 {
+		return true
+	}
+
+	baseHost := w.extractBaseHost(fileURL)
+	countPtr, _ := w.binaryCounters.LoadOrStore(baseHost, new(int64))
+	count := atomic.AddInt64(countPtr.(*int64), 1)
+	if count <= int64(w.config.MaxBinariesPerHost) {
+		return true
+	}
+
+	if count == int64(w.config.MaxBinariesPerHost)+1 {
+		w.logger.Info("Host reached max_binaries_per_host (%d), further binaries are not recorded: %s", w.config.MaxBinariesPerHost, baseHost)
+	}
+	atomic.AddInt64(&w.suppressedBinaries, 1)
+	return false
+}
+
+// GetSuppressedBinaries returns the number of binaries not recorded because of max_binaries_per_host
+func (w *Worker) GetSuppressedBinaries() int {
+	return int(atomic.LoadInt64(&w.suppressedBinaries))
+}
+
+// LinksCapped reports whether file recording stopped because max_total_links_global was reached
+func (w *Worker) LinksCapped() bool {
+	return atomic.LoadInt32(&w.linksCapped) == 1
+}
+
+// ChecksCapped reports whether file checking stopped because max_checks was reached
+func (w *Worker) ChecksCapped() bool {
+	return atomic.LoadInt32(&w.checksCapped) == 1
+}
+
+// GetDownloadedFiles returns the number of binaries saved with download_binaries
+func (w *Worker) GetDownloadedFiles() int {
+	return int(atomic.LoadInt64(&w.downloadedFiles))
+}
+
+// GetMismatchFiles returns the number of extension/content-type mismatches found
+func (w *Worker) GetMismatchFiles() int {
+	return int(atomic.LoadInt64(&w.mismatchFiles))
+}
+
+// GetSubpathListings returns the number of listings found via root_subpaths probing
+func (w *Worker) GetSubpathListings() int {
+	return int(atomic.LoadInt64(&w.subpathListings))
+}
+
+// fileExtension returns the lowercase extension of a file URL, or "(none)"
+func fileExtension(fileURL string) string {
+	extension := ""
+	if parsedURL, err := url.Parse(fileURL); err == nil {
+		extension = strings.ToLower(path.Ext(parsedURL.Path))
+	}
+	if extension == "" {
+		return "(none)"
+	}
+	return extension
+}
+
+// isHTMLContentType checks if a Content-Type may contain a directory listing
+// A missing Content-Type is accepted since many old servers omit it
+func isHTMLContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	contentType = strings.ToLower(contentType)
+	return strings.Contains(contentType, "text/html") || strings.Contains(contentType, "application/xhtml+xml")
 }
 
 // extractBaseHost extracts the base host (IP only) from a full URL
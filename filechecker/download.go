@@ -0,0 +1,243 @@
+package filechecker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Download limits for confirmed binaries
+const (
+	defaultMaxDownloadSize = 100 << 20 // 100 MB
+	downloadTimeout        = 10 * time.Minute
+)
+
+// DownloadResult describes a file saved by DownloadFile
+type DownloadResult struct {
+	Size        int64  // Size of the saved file in bytes
+	ResumedFrom int64  // Bytes kept from an interrupted download (0 = downloaded from the start)
+	SHA256      string // Hex encoded SHA-256 of the saved file
+	Existing    bool   // The file was already completely downloaded by an earlier run
+}
+
+// DownloadFile streams a file to destPath, giving up on files larger than maxSize bytes
+// The file is written to destPath.part and only renamed once its size matches the size
+// announced by the server. Interrupted downloads keep the partial file and are resumed
+// with a Range request on the next call for the same destPath.
+func (fc *FileChecker) DownloadFile(ctx context.Context, fileURL, destPath string, maxSize int64) (*DownloadResult, error) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxDownloadSize
+	}
+
+	// Files completed by an earlier run are not fetched again
+	if info, err := os.Stat(destPath); err == nil {
+		hash, err := fileSHA256(destPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash existing download: %w", err)
+		}
+		return &DownloadResult{Size: info.Size(), SHA256: hash, Existing: true}, nil
+	}
+
+	partPath := destPath + ".part"
+	validatorPath := partPath + ".validator"
+
+	var offset int64
+	validator := ""
+	if info, err := os.Stat(partPath); err == nil && info.Size() > 0 {
+		offset = info.Size()
+		if data, err := os.ReadFile(validatorPath); err == nil {
+			validator = strings.TrimSpace(string(data))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", fc.userAgents.Pick(fileURL))
+	req.Header.Set("Accept", "*/*")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		// Only resume if the file is unchanged, otherwise the server sends it whole
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+
+	// The check timeout is meant for the first bytes only, whole files use downloadTimeout
+	client := &http.Client{
+		Transport:     fc.httpClient.Transport,
+		CheckRedirect: fc.httpClient.CheckRedirect,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1) // Expected size of the complete file, -1 if unknown
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return nil, fmt.Errorf("unexpected Content-Range %q when resuming at byte %d", resp.Header.Get("Content-Range"), offset)
+		}
+		total = size
+		fc.logger.Debug("Resuming download of %s at byte %d", fileURL, offset)
+
+	case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file may already hold every byte of the file
+		_, size, _ := parseContentRange(resp.Header.Get("Content-Range"))
+		if size != offset {
+			removePartialDownload(partPath)
+			return nil, fmt.Errorf("cannot resume download at byte %d, partial file discarded", offset)
+		}
+		return finishDownload(partPath, destPath, offset, offset)
+
+	case resp.StatusCode == http.StatusOK:
+		// Fresh download, or the server ignored the range or the file changed
+		if offset > 0 {
+			fc.logger.Debug("Server sent the whole file for %s, restarting download", fileURL)
+			offset = 0
+		}
+		total = resp.ContentLength
+
+	default:
+		return nil, fmt.Errorf("server returned non-OK status: %d", resp.StatusCode)
+	}
+
+	if total > maxSize {
+		removePartialDownload(partPath)
+		return nil, fmt.Errorf("file size %d exceeds max_download_size of %d bytes", total, maxSize)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if offset == 0 {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+
+		// Remember the version of the file so a later resume can detect changes
+		if validator := resumeValidator(resp.Header); validator != "" {
+			os.WriteFile(validatorPath, []byte(validator), 0600)
+		} else {
+			os.Remove(validatorPath)
+		}
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open download file: %w", err)
+	}
+
+	// Read one byte past the limit to detect oversized files without a Content-Length
+	written, err := io.Copy(file, io.LimitReader(resp.Body, maxSize-offset+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Keep the partial file so the next attempt resumes where this one stopped
+		return nil, fmt.Errorf("download interrupted after %d bytes: %w", offset+written, err)
+	}
+
+	size := offset + written
+	if size > maxSize {
+		removePartialDownload(partPath)
+		return nil, fmt.Errorf("file exceeds max_download_size of %d bytes", maxSize)
+	}
+	if total >= 0 && size != total {
+		if size > total {
+			removePartialDownload(partPath)
+		}
+		return nil, fmt.Errorf("incomplete download: got %d of %d bytes", size, total)
+	}
+
+	return finishDownload(partPath, destPath, size, offset)
+}
+
+// finishDownload hashes a complete partial file and moves it to its final name
+func finishDownload(partPath, destPath string, size, resumedFrom int64) (*DownloadResult, error) {
+	hash, err := fileSHA256(partPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash download: %w", err)
+	}
+	if err := os.Rename(partPath, destPath); err != nil {
+		return nil, fmt.Errorf("failed to move download into place: %w", err)
+	}
+	os.Remove(partPath + ".validator")
+
+	return &DownloadResult{Size: size, ResumedFrom: resumedFrom, SHA256: hash}, nil
+}
+
+// removePartialDownload deletes a partial file that cannot be resumed
+func removePartialDownload(partPath string) {
+	os.Remove(partPath)
+	os.Remove(partPath + ".validator")
+}
+
+// resumeValidator returns the If-Range value of a response: a strong ETag or Last-Modified
+func resumeValidator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// parseContentRange parses "bytes start-end/total" and "bytes */total"
+// The total is -1 if the server sent "*"
+func parseContentRange(value string) (int64, int64, bool) {
+	rangeSpec, ok := strings.CutPrefix(strings.TrimSpace(value), "bytes ")
+	if !ok {
+		return 0, -1, false
+	}
+	positions, totalStr, ok := strings.Cut(rangeSpec, "/")
+	if !ok {
+		return 0, -1, false
+	}
+
+	total := int64(-1)
+	if totalStr != "*" {
+		parsed, err := strconv.ParseInt(totalStr, 10, 64)
+		if err != nil {
+			return 0, -1, false
+		}
+		total = parsed
+	}
+
+	if positions == "*" {
+		return 0, total, true
+	}
+	startStr, _, ok := strings.Cut(positions, "-")
+	if !ok {
+		return 0, -1, false
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, -1, false
+	}
+	return start, total, true
+}
+
+// fileSHA256 returns the hex encoded SHA-256 of a file
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,52 @@
+package filechecker
+
+import (
+	"fmt"
+	"math"
+)
+
+// minEntropySample is the smallest prefix whose entropy is meaningful
+const minEntropySample = 64
+
+// SetEntropyThreshold annotates binary findings whose prefix has a Shannon entropy of at least
+// threshold bits per byte as likely packed/encrypted (0 = disabled)
+// Uses only bytes that are read anyway, so it costs no extra requests
+func (fc *FileChecker) SetEntropyThreshold(threshold float64) {
+	fc.entropyThreshold = threshold
+}
+
+// entropyAnnotation returns a note for high-entropy file prefixes or an empty string
+func (fc *FileChecker) entropyAnnotation(prefix []byte) string {
+	if fc.entropyThreshold <= 0 || len(prefix) < minEntropySample {
+		return ""
+	}
+
+	entropy := shannonEntropy(prefix)
+	if entropy < fc.entropyThreshold {
+		return ""
+	}
+	return fmt.Sprintf("entropy %.2f: likely packed/encrypted", entropy)
+}
+
+// shannonEntropy returns the Shannon entropy of data in bits per byte (0 to 8)
+func shannonEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	entropy := 0.0
+	total := float64(len(data))
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / total
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -1,15 +1,21 @@
 package filechecker
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"censei/logging"
+	"censei/netproxy"
+	"censei/useragent"
 )
 
 // FileChecker handles file verification operations without downloading
@@ -18,6 +24,15 @@ type FileChecker struct {
 	logger         *logging.Logger
 	checkEnabled   bool
 	targetFileName string
+	userAgents     *useragent.Picker
+	signatureCheck bool // Verify files by their first and last bytes
+	signatureBytes int
+
+	// Extensions that are content-verified (empty = all files), see SetCheckExtensions
+	checkExtensions map[string]bool
+
+	// Minimum prefix entropy flagged as packed/encrypted (0 = disabled), see SetEntropyThreshold
+	entropyThreshold float64
 }
 
 // NewFileChecker creates a new file checker instance with optimized connection pooling
@@ -59,9 +74,58 @@ func (fc *FileChecker) Configure(enabled bool, targetFileName string) {
 	fc.targetFileName = targetFileName
 }
 
-// isBinaryContentType checks if a content type indicates binary content
+// SetConnectionPool overrides the idle connection limits of the transport
+// Values of 0 keep the defaults (200 total, 20 per host)
+func (fc *FileChecker) SetConnectionPool(maxIdleConns, maxIdleConnsPerHost int) {
+	transport, ok := fc.httpClient.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+	if maxIdleConns > 0 {
+		transport.MaxIdleConns = maxIdleConns
+	}
+	if maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	}
+}
+
+// SetProxy routes all requests through an HTTP, HTTPS or SOCKS5 proxy
+// Connections are tunnelled at the dial level like the crawler's, TLS is negotiated with the host
+// An empty proxyURL keeps direct connections
+func (fc *FileChecker) SetProxy(proxyURL string) error {
+	transport, ok := fc.httpClient.Transport.(*http.Transport)
+	if !ok || proxyURL == "" {
+		return nil
+	}
+
+	dialer, err := netproxy.New(proxyURL, &net.Dialer{Timeout: fc.httpClient.Timeout, KeepAlive: 30 * time.Second})
+	if err != nil {
+		return err
+	}
+	transport.DialContext = dialer.DialContext
+	return nil
+}
+
+// SetHTTP2 enables HTTP/2 negotiation via ALPN for HTTPS hosts
+func (fc *FileChecker) SetHTTP2(enabled bool) {
+	if transport, ok := fc.httpClient.Transport.(*http.Transport); ok {
+		transport.ForceAttemptHTTP2 = enabled
+	}
+}
+
+// CloseIdleConnections closes keep-alive connections that are currently idle
+func (fc *FileChecker) CloseIdleConnections() {
+	fc.httpClient.CloseIdleConnections()
+}
+
+// SetUserAgents configures how the User-Agent is chosen for each request
+func (fc *FileChecker) SetUserAgents(picker *useragent.Picker) {
+	fc.userAgents = picker
+}
+
+// IsBinaryContentType checks if a content type indicates binary content
 // Optimized helper to avoid code duplication and enable early exit
-func isBinaryContentType(contentType string) bool {
+func IsBinaryContentType(contentType string) bool {
 	binaryTypes := []string{
 		// Generic binary types
 		"application/octet-stream",
@@ -164,7 +228,7 @@ func isBinaryContentType(contentType string) bool {
 
 // CheckSpecificFile checks if a specific file exists at the given URL
 // and verifies its content type without downloading the full file
-func (fc *FileChecker) CheckSpecificFile(baseURL, fileName string) (bool, string, error) {
+func (fc *FileChecker) CheckSpecificFile(ctx context.Context, baseURL, fileName string) (bool, string, error) {
 	if !fc.checkEnabled {
 		return false, "", fmt.Errorf("file checking functionality is disabled")
 	}
@@ -182,13 +246,13 @@ func (fc *FileChecker) CheckSpecificFile(baseURL, fileName string) (bool, string
 	fc.logger.Info("Checking for specific file: %s", fileURL)
 
 	// Create the request
-	req, err := http.NewRequest("GET", fileURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
 	if err != nil {
 		return false, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers to avoid detection/blocking
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; CenseiBot/1.0)")
+	req.Header.Set("User-Agent", fc.userAgents.Pick(fileURL))
 	req.Header.Set("Accept", "*/*")
 
 	// Execute the request
@@ -213,7 +277,7 @@ func (fc *FileChecker) CheckSpecificFile(baseURL, fileName string) (bool, string
 	}
 
 	// Check for binary content types using optimized helper
-	isBinaryContent := isBinaryContentType(contentType)
+	isBinaryContent := IsBinaryContentType(contentType)
 
 	// Read a small portion of the body to verify content type
 	// This helps avoid downloading the entire file
@@ -225,6 +289,9 @@ func (fc *FileChecker) CheckSpecificFile(baseURL, fileName string) (bool, string
 
 	// Log the result
 	if isBinaryContent {
+		if note := fc.entropyAnnotation(buffer[:n]); note != "" {
+			contentType = fmt.Sprintf("%s (%s)", contentType, note)
+		}
 		fc.logger.Info("Found '%s' at %s with Content-Type: %s", fileName, fileURL, contentType)
 		return true, contentType, nil
 	}
@@ -234,6 +301,22 @@ func (fc *FileChecker) CheckSpecificFile(baseURL, fileName string) (bool, string
 	return false, contentType, fmt.Errorf("file is not binary content")
 }
 
+// SetCheckExtensions limits content checks to files with the given extensions (e.g. ".exe")
+// Files with other extensions are not checked; they stay filtered but are never reported as binaries
+func (fc *FileChecker) SetCheckExtensions(extensions []string) {
+	fc.checkExtensions = make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		fc.checkExtensions[ext] = true
+	}
+}
+
 // ShouldCheck determines if a file should be checked
 func (fc *FileChecker) ShouldCheck(fileURL string) bool {
 	// If check is not enabled, don't check anything
@@ -247,12 +330,19 @@ func (fc *FileChecker) ShouldCheck(fileURL string) bool {
 		return baseName == fc.targetFileName
 	}
 
-	// If no target filename, check all files
+	// If no target filename, check all files or only the configured extensions
+	if len(fc.checkExtensions) > 0 {
+		filePath := fileURL
+		if parsedURL, err := url.Parse(fileURL); err == nil {
+			filePath = parsedURL.Path
+		}
+		return fc.checkExtensions[strings.ToLower(path.Ext(filePath))]
+	}
 	return true
 }
 
 // CheckFileURL checks if a file at the given URL is binary content
-func (fc *FileChecker) CheckFileURL(fileURL string) (bool, string, error) {
+func (fc *FileChecker) CheckFileURL(ctx context.Context, fileURL string) (bool, string, error) {
 	if !fc.checkEnabled {
 		return false, "", fmt.Errorf("file checking functionality is disabled")
 	}
@@ -260,13 +350,13 @@ func (fc *FileChecker) CheckFileURL(fileURL string) (bool, string, error) {
 	fc.logger.Debug("Checking file: %s", fileURL)
 
 	// Create the request
-	req, err := http.NewRequest("HEAD", fileURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "HEAD", fileURL, nil)
 	if err != nil {
 		return false, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; CenseiBot/1.0)")
+	req.Header.Set("User-Agent", fc.userAgents.Pick(fileURL))
 	req.Header.Set("Accept", "*/*")
 
 	// Execute HEAD request first to check content type efficiently
@@ -291,10 +381,28 @@ func (fc *FileChecker) CheckFileURL(fileURL string) (bool, string, error) {
 	}
 
 	// Check for binary content types using optimized helper
-	isBinaryContent := isBinaryContentType(contentType)
+	isBinaryContent := IsBinaryContentType(contentType)
+
+	// Verify by file signatures at both ends if enabled
+	var head []byte
+	if fc.signatureCheck {
+		var signature string
+		signature, head, err = fc.checkSignatures(ctx, fileURL)
+		if err != nil {
+			fc.logger.Debug("Signature check failed for %s: %v", fileURL, err)
+		} else if signature != "" {
+			fc.logger.Debug("Signature match for %s: %s", fileURL, signature)
+			contentType = fmt.Sprintf("%s (signature: %s)", contentType, signature)
+			isBinaryContent = true
+		}
+	}
 
 	// Log the result
 	if isBinaryContent {
+		// The head is only available with signature checks, HEAD requests carry no body
+		if note := fc.entropyAnnotation(head); note != "" {
+			contentType = fmt.Sprintf("%s (%s)", contentType, note)
+		}
 		fc.logger.Info("Found binary file at %s with Content-Type: %s", fileURL, contentType)
 		return true, contentType, nil
 	}
@@ -0,0 +1,121 @@
+package filechecker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultSignatureBytes is the number of bytes fetched from each end of a file
+const defaultSignatureBytes = 512
+
+// headSignatures are magic bytes found at the start of binary files
+var headSignatures = []struct {
+	magic []byte
+	name  string
+}{
+	{[]byte("MZ"), "PE executable"},
+	{[]byte("\x7fELF"), "ELF executable"},
+	{[]byte("\xcf\xfa\xed\xfe"), "Mach-O executable"},
+	{[]byte("\xce\xfa\xed\xfe"), "Mach-O executable"},
+	{[]byte("\xca\xfe\xba\xbe"), "Mach-O universal binary"},
+	{[]byte("PK\x03\x04"), "ZIP archive"},
+	{[]byte("Rar!\x1a\x07"), "RAR archive"},
+	{[]byte("7z\xbc\xaf\x27\x1c"), "7-Zip archive"},
+	{[]byte("\x1f\x8b"), "GZIP archive"},
+	{[]byte("BZh"), "BZIP2 archive"},
+	{[]byte("\xfd7zXZ\x00"), "XZ archive"},
+	{[]byte("MSCF"), "CAB archive"},
+	{[]byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), "OLE document (MSI/DOC)"},
+	{[]byte("L\x00\x00\x00\x01\x14\x02\x00"), "Windows shortcut"},
+	{[]byte("!<arch>\n"), "Debian package"},
+	{[]byte("\xed\xab\xee\xdb"), "RPM package"},
+}
+
+// tailSignatures are markers found near the end of files (appended archives, trailers)
+var tailSignatures = []struct {
+	marker []byte
+	name   string
+}{
+	{[]byte("PK\x05\x06"), "ZIP central directory"},
+	{[]byte("koly"), "DMG trailer"},
+}
+
+// SetSignatureCheck enables verification by fetching the first and last bytes of each file
+// Costs up to two extra ranged requests per checked file
+func (fc *FileChecker) SetSignatureCheck(enabled bool, numBytes int) {
+	if numBytes <= 0 {
+		numBytes = defaultSignatureBytes
+	}
+	fc.signatureCheck = enabled
+	fc.signatureBytes = numBytes
+}
+
+// checkSignatures fetches the first and last bytes of a file and matches known signatures
+// Returns a description of the matched signatures (empty if none matched) and the file head
+func (fc *FileChecker) checkSignatures(ctx context.Context, fileURL string) (string, []byte, error) {
+	head, err := fc.fetchRange(ctx, fileURL, fmt.Sprintf("bytes=0-%d", fc.signatureBytes-1), false)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch file head: %w", err)
+	}
+
+	var matches []string
+	for _, signature := range headSignatures {
+		if bytes.HasPrefix(head, signature.magic) {
+			matches = append(matches, signature.name)
+			break
+		}
+	}
+
+	// The tail is best effort - servers without range support only return the head
+	tail, err := fc.fetchRange(ctx, fileURL, fmt.Sprintf("bytes=-%d", fc.signatureBytes), true)
+	if err != nil {
+		fc.logger.Debug("Failed to fetch file tail for %s: %v", fileURL, err)
+	}
+	for _, signature := range tailSignatures {
+		if bytes.Contains(tail, signature.marker) {
+			matches = append(matches, signature.name)
+			break
+		}
+	}
+
+	// A PE or ELF with an archive trailer is a self-extracting archive or has an appended payload
+	if len(matches) == 2 && (matches[0] == "PE executable" || matches[0] == "ELF executable") && matches[1] == "ZIP central directory" {
+		matches = append(matches, "appended archive")
+	}
+
+	return strings.Join(matches, " + "), head, nil
+}
+
+// fetchRange performs a ranged GET request and returns up to signatureBytes of the body
+// With requirePartial, responses that ignore the Range header are rejected
+func (fc *FileChecker) fetchRange(ctx context.Context, fileURL, byteRange string, requirePartial bool) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, fc.httpClient.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", fc.userAgents.Pick(fileURL))
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Range", byteRange)
+
+	resp, err := fc.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		if requirePartial || resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("server returned status %d for range %s", resp.StatusCode, byteRange)
+		}
+	}
+
+	// Never read more than requested, even if the server sends the whole file
+	return io.ReadAll(io.LimitReader(resp.Body, int64(fc.signatureBytes)))
+}
@@ -0,0 +1,31 @@
+package filechecker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// FileSize reads the size of a file from the Content-Length of a HEAD request
+// Returns -1 if the server does not announce a length
+// Works whether or not content checks are enabled
+func (fc *FileChecker) FileSize(ctx context.Context, fileURL string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", fileURL, nil)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", fc.userAgents.Pick(fileURL))
+	req.Header.Set("Accept", "*/*")
+
+	resp, err := fc.httpClient.Do(req)
+	if err != nil {
+		return -1, fmt.Errorf("failed to request file size: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return -1, fmt.Errorf("server returned non-OK status: %d", resp.StatusCode)
+	}
+
+	return resp.ContentLength, nil
+}
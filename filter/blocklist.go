@@ -2,6 +2,7 @@ package filter
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -11,7 +12,19 @@ import (
 	"censei/logging"
 )
 
+// BlockStore is a store of hosts blocked after exceeding skip limits
+// Blocklist (file-based) is the default, RedisBlocklist is shared between scanners
+type BlockStore interface {
+	Load() error
+	Save() error
+	IsBlocked(ctx context.Context, hostname string) bool
+	AddHost(hostname string) bool
+	GetBlockedCount() int
+	Close() error
+}
+
 // Blocklist manages a persistent list of blocked hosts
+// A disabled Blocklist is a no-op BlockStore
 type Blocklist struct {
 	hosts      map[string]time.Time // hostname -> timestamp when blocked
 	filePath   string
@@ -148,7 +161,7 @@ func (b *Blocklist) Save() error {
 }
 
 // IsBlocked checks if a host is in the blocklist
-func (b *Blocklist) IsBlocked(hostname string) bool {
+func (b *Blocklist) IsBlocked(_ context.Context, hostname string) bool {
 	if !b.enabled {
 		return false
 	}
@@ -161,9 +174,10 @@ func (b *Blocklist) IsBlocked(hostname string) bool {
 }
 
 // AddHost adds a host to the blocklist
-func (b *Blocklist) AddHost(hostname string) {
+// Returns true if the host was not blocked before
+func (b *Blocklist) AddHost(hostname string) bool {
 	if !b.enabled {
-		return
+		return false
 	}
 
 	b.mu.Lock()
@@ -180,7 +194,9 @@ func (b *Blocklist) AddHost(hostname string) {
 		default:
 			// Channel already has a pending save signal, skip
 		}
+		return true
 	}
+	return false
 }
 
 // saveWorker runs in background and handles debounced saves
@@ -0,0 +1,54 @@
+package filter
+
+import (
+	"strings"
+)
+
+// CategoryOther is the category of extensions without a mapping
+const CategoryOther = "other"
+
+// defaultCategories maps file categories to their extensions
+var defaultCategories = map[string][]string{
+	"document":   {".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".odt", ".ods", ".odp", ".rtf", ".txt", ".csv", ".md", ".epub"},
+	"archive":    {".zip", ".rar", ".7z", ".tar", ".gz", ".tgz", ".bz2", ".xz", ".zst", ".cab", ".iso", ".img", ".dmg"},
+	"executable": {".exe", ".dll", ".msi", ".elf", ".bin", ".so", ".dylib", ".apk", ".jar", ".deb", ".rpm", ".scr", ".sys", ".com"},
+	"script":     {".sh", ".bat", ".cmd", ".ps1", ".vbs", ".js", ".py", ".pl", ".php", ".hta", ".lnk"},
+	"media":      {".jpg", ".jpeg", ".png", ".gif", ".bmp", ".svg", ".webp", ".ico", ".mp3", ".wav", ".flac", ".mp4", ".mkv", ".avi", ".mov", ".webm"},
+	"data":       {".sql", ".db", ".sqlite", ".json", ".xml", ".yaml", ".yml", ".bak", ".log", ".conf", ".ini", ".env", ".pem", ".key"},
+}
+
+// Categorizer maps file extensions to higher-level categories (document, archive, executable, ...)
+type Categorizer struct {
+	categories map[string]string // extension -> category
+}
+
+// NewCategorizer creates a categorizer from the default mapping
+// Overrides assign extensions to (new or existing) categories, replacing their default category
+func NewCategorizer(overrides map[string][]string) *Categorizer {
+	c := &Categorizer{categories: make(map[string]string)}
+	for category, extensions := range defaultCategories {
+		c.assign(category, extensions)
+	}
+	for category, extensions := range overrides {
+		c.assign(strings.ToLower(category), extensions)
+	}
+	return c
+}
+
+// assign maps extensions to a category, normalized like filter extensions
+func (c *Categorizer) assign(category string, extensions []string) {
+	for _, ext := range extensions {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		c.categories[strings.ToLower(ext)] = category
+	}
+}
+
+// Category returns the category of a file extension (including the dot)
+func (c *Categorizer) Category(extension string) string {
+	if category, ok := c.categories[strings.ToLower(extension)]; ok {
+		return category
+	}
+	return CategoryOther
+}
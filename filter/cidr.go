@@ -0,0 +1,40 @@
+package filter
+
+import (
+	"net"
+	"strings"
+)
+
+// parseNetwork parses a CIDR entry (e.g. 10.0.0.0/8) into a network
+// Returns nil if the entry is not in CIDR notation
+func parseNetwork(entry string) *net.IPNet {
+	if !strings.Contains(entry, "/") {
+		return nil
+	}
+
+	_, network, err := net.ParseCIDR(entry)
+	if err != nil {
+		return nil
+	}
+	return network
+}
+
+// containsIP checks if a hostname is an IP address inside any of the networks
+// Hostnames that are not IP addresses never match
+func containsIP(networks []*net.IPNet, hostname string) bool {
+	if len(networks) == 0 {
+		return false
+	}
+
+	ip := net.ParseIP(strings.Trim(hostname, "[]"))
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
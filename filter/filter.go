@@ -1,21 +1,26 @@
 package filter
 
 import (
+	"net/url"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"censei/logging"
 )
 
 // Filter handles file filtering based on extensions with O(1) map lookup
+// and optional regular expressions matched against the URL path
 type Filter struct {
 	extensionMap map[string]bool
+	patterns     []*regexp.Regexp
 	logger       *logging.Logger
 }
 
-// NewFilter creates a new filter with the given extensions
-// Extensions are normalized once during initialization for optimal performance
-func NewFilter(extensions []string, logger *logging.Logger) *Filter {
+// NewFilter creates a new filter with the given extensions and regex patterns
+// Extensions are normalized and patterns compiled once during initialization for optimal performance
+// Patterns are validated when queries are loaded, invalid ones are logged and ignored
+func NewFilter(extensions []string, patterns []string, logger *logging.Logger) *Filter {
 	// Create map for O(1) lookup instead of O(n) slice iteration
 	extensionMap := make(map[string]bool, len(extensions))
 
@@ -29,16 +34,32 @@ func NewFilter(extensions []string, logger *logging.Logger) *Filter {
 		extensionMap[strings.ToLower(ext)] = true
 	}
 
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Error("Ignoring invalid filter_regex pattern %q: %v", pattern, err)
+			continue
+		}
+		compiled = append(compiled, re)
+	}
+
 	return &Filter{
 		extensionMap: extensionMap,
+		patterns:     compiled,
 		logger:       logger,
 	}
 }
 
-// ShouldFilter checks if a file should be filtered based on its extension
+// ShouldFilter checks if a file should be filtered based on its extension or a regex pattern
 // Uses O(1) map lookup for optimal performance
 func (f *Filter) ShouldFilter(fileURL string) bool {
-	// No filters defined
+	// A file passes if it matches any extension or any pattern
+	if f.ShouldFilterRegex(fileURL) {
+		return true
+	}
+
+	// No extension filters defined
 	if len(f.extensionMap) == 0 {
 		return false
 	}
@@ -55,6 +76,35 @@ func (f *Filter) ShouldFilter(fileURL string) bool {
 	return false
 }
 
+// ShouldFilterRegex checks if the URL path of a file matches any of the regex patterns
+func (f *Filter) ShouldFilterRegex(fileURL string) bool {
+	if len(f.patterns) == 0 {
+		return false
+	}
+
+	filePath := fileURL
+	if parsedURL, err := url.Parse(fileURL); err == nil {
+		filePath = parsedURL.Path
+	}
+
+	for _, pattern := range f.patterns {
+		if pattern.MatchString(filePath) {
+			f.logger.Debug("File %s matches filter pattern %s", fileURL, pattern)
+			return true
+		}
+	}
+	return false
+}
+
+// GetFilterPatterns returns the regex patterns of the filter
+func (f *Filter) GetFilterPatterns() []string {
+	patterns := make([]string, 0, len(f.patterns))
+	for _, pattern := range f.patterns {
+		patterns = append(patterns, pattern.String())
+	}
+	return patterns
+}
+
 // GetFilterExtensions returns the current filter extensions as a slice
 func (f *Filter) GetFilterExtensions() []string {
 	// Convert map keys back to slice for compatibility
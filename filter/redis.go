@@ -0,0 +1,223 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"censei/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis key and timing settings
+const (
+	redisBlocklistKey = "censei:blocklist" // hash: hostname -> RFC3339 timestamp
+	redisTimeout      = 5 * time.Second    // Dial, read and write timeout of each command
+	redisCacheTTL     = 30 * time.Second   // How long a lookup result is reused before asking Redis again
+	redisRetryAfter   = 30 * time.Second   // How long Redis is skipped after a failed command
+)
+
+// RedisBlocklist stores blocked hosts in a Redis hash shared by all scanners
+// Lookups are cached for redisCacheTTL, so blocks earned by another scanner take effect
+// here within that time; blocks added by this scanner apply immediately. After a Redis
+// error, Redis is skipped for redisRetryAfter and uncached hosts are treated as not
+// blocked, so workers never queue behind an unreachable server.
+type RedisBlocklist struct {
+	client  *redis.Client
+	address string
+	logger  *logging.Logger
+
+	// Recent IsBlocked results per hostname, see redisCacheTTL
+	cache map[string]redisCacheEntry
+
+	// Redis is not contacted before this time, see redisRetryAfter
+	unavailableUntil time.Time
+	mu               sync.Mutex
+}
+
+// redisCacheEntry is a cached lookup result
+type redisCacheEntry struct {
+	blocked bool
+	expires time.Time
+}
+
+// NewRedisBlocklist connects to Redis using a URL like redis://[:password@]host:6379/0
+// rediss:// connects with TLS
+func NewRedisBlocklist(redisURL string, logger *logging.Logger) (*RedisBlocklist, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	options.DialTimeout = redisTimeout
+	options.ReadTimeout = redisTimeout
+	options.WriteTimeout = redisTimeout
+	options.ContextTimeoutEnabled = true
+	options.MaxRetries = 1 // Reconnect once if a pooled connection was dropped
+
+	r := &RedisBlocklist{
+		client:  redis.NewClient(options),
+		address: options.Addr,
+		logger:  logger,
+		cache:   make(map[string]redisCacheEntry),
+	}
+
+	// Connect eagerly so misconfiguration is reported at startup
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		r.client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %w", r.address, err)
+	}
+
+	return r, nil
+}
+
+// Load reports the number of hosts already blocked in the shared store
+func (r *RedisBlocklist) Load() error {
+	count, err := r.count()
+	if err != nil {
+		return fmt.Errorf("failed to read Redis blocklist: %w", err)
+	}
+
+	r.logger.Info("Shared Redis blocklist at %s contains %d blocked hosts", r.address, count)
+	return nil
+}
+
+// Save is a no-op, hosts are written to Redis as soon as they are blocked
+func (r *RedisBlocklist) Save() error {
+	return nil
+}
+
+// IsBlocked checks if a host is in the shared blocklist
+func (r *RedisBlocklist) IsBlocked(ctx context.Context, hostname string) bool {
+	if blocked, ok := r.cached(hostname); ok {
+		return blocked
+	}
+	if !r.available() {
+		return false
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	blocked, err := r.client.HExists(lookupCtx, redisBlocklistKey, hostname).Result()
+	if err != nil {
+		// A stopped scan is not a Redis failure
+		if ctx.Err() == nil {
+			r.markUnavailable(err)
+		}
+		return false
+	}
+
+	r.cacheResult(hostname, blocked)
+	return blocked
+}
+
+// AddHost adds a host to the shared blocklist
+// The block applies locally even if Redis cannot be reached
+// Returns true if this scanner blocked the host first
+func (r *RedisBlocklist) AddHost(hostname string) bool {
+	blockedAt := time.Now()
+	r.cacheResult(hostname, true)
+
+	if !r.available() {
+		r.logger.Error("Failed to add host %s to Redis blocklist: Redis unavailable", hostname)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	// HSETNX returns false if another scanner blocked the host first
+	added, err := r.client.HSetNX(ctx, redisBlocklistKey, hostname, blockedAt.Format(time.RFC3339)).Result()
+	if err != nil {
+		r.markUnavailable(err)
+		r.logger.Error("Failed to add host %s to Redis blocklist: %v", hostname, err)
+		return false
+	}
+
+	if added {
+		r.logger.Info("Added host to blocklist: %s", hostname)
+	}
+	return added
+}
+
+// GetBlockedCount returns the number of hosts in the shared blocklist
+func (r *RedisBlocklist) GetBlockedCount() int {
+	count, err := r.count()
+	if err != nil {
+		r.logger.Error("Failed to count Redis blocklist: %v", err)
+		return 0
+	}
+	return count
+}
+
+// Close closes the Redis connections
+func (r *RedisBlocklist) Close() error {
+	if err := r.client.Close(); err != nil {
+		return err
+	}
+	r.logger.Debug("Redis blocklist closed successfully")
+	return nil
+}
+
+// available reports whether Redis may be contacted, see redisRetryAfter
+func (r *RedisBlocklist) available() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !time.Now().Before(r.unavailableUntil)
+}
+
+// markUnavailable stops contacting Redis for redisRetryAfter, logging once per outage
+func (r *RedisBlocklist) markUnavailable(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(r.unavailableUntil) {
+		return
+	}
+	r.unavailableUntil = now.Add(redisRetryAfter)
+	r.logger.Error("WARNING: Redis blocklist at %s failed (%v) - treating uncached hosts as not blocked for %s",
+		r.address, err, redisRetryAfter)
+}
+
+// cached returns an unexpired lookup result for a hostname
+func (r *RedisBlocklist) cached(hostname string) (bool, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.cache[hostname]
+	if !ok || time.Now().After(entry.expires) {
+		return false, false
+	}
+	return entry.blocked, true
+}
+
+// cacheResult remembers a lookup result for redisCacheTTL
+// Expired entries are dropped whenever the cache has doubled since the last sweep
+func (r *RedisBlocklist) cacheResult(hostname string, blocked bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.cache[hostname] = redisCacheEntry{blocked: blocked, expires: now.Add(redisCacheTTL)}
+	if len(r.cache) >= 1024 && len(r.cache)&(len(r.cache)-1) == 0 {
+		for name, entry := range r.cache {
+			if now.After(entry.expires) {
+				delete(r.cache, name)
+			}
+		}
+	}
+}
+
+// count returns the size of the blocklist hash
+func (r *RedisBlocklist) count() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	count, err := r.client.HLen(ctx, redisBlocklistKey).Result()
+	return int(count), err
+}
@@ -0,0 +1,214 @@
+package filter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"censei/logging"
+)
+
+// fakeRedis answers HEXISTS, HSETNX, HLEN and PING on a single hash
+// Other commands, such as the client handshake, get an error reply
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	hash     map[string]string
+	lookups  atomic.Int64
+	down     atomic.Bool // Drop connections instead of answering
+}
+
+func startFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeRedis{listener: listener, hash: make(map[string]string)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (f *fakeRedis) url() string {
+	return "redis://" + f.listener.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		command, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if command[0] == "HEXISTS" {
+			f.lookups.Add(1)
+		}
+		if f.down.Load() {
+			return
+		}
+
+		f.mu.Lock()
+		switch command[0] {
+		case "PING":
+			fmt.Fprint(conn, "+PONG\r\n")
+		case "HEXISTS":
+			_, ok := f.hash[command[2]]
+			fmt.Fprintf(conn, ":%d\r\n", boolInt(ok))
+		case "HSETNX":
+			_, ok := f.hash[command[2]]
+			if !ok {
+				f.hash[command[2]] = command[3]
+			}
+			fmt.Fprintf(conn, ":%d\r\n", boolInt(!ok))
+		case "HLEN":
+			fmt.Fprintf(conn, ":%d\r\n", len(f.hash))
+		default:
+			fmt.Fprint(conn, "-ERR unknown command\r\n")
+		}
+		f.mu.Unlock()
+	}
+}
+
+// readCommand reads one command sent as a RESP array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	readLine := func(prefix byte) (int, error) {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+		if line[0] != prefix {
+			return 0, fmt.Errorf("unexpected line %q", line)
+		}
+		return strconv.Atoi(strings.TrimSpace(line[1:]))
+	}
+
+	count, err := readLine('*')
+	if err != nil {
+		return nil, err
+	}
+	command := make([]string, count)
+	for i := range command {
+		size, err := readLine('$')
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		command[i] = string(data[:size])
+	}
+	command[0] = strings.ToUpper(command[0])
+	return command, nil
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestRedisBlocklistCachesLookups(t *testing.T) {
+	server := startFakeRedis(t)
+	blocklist, err := NewRedisBlocklist(server.url(), logging.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blocklist.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if blocklist.IsBlocked(ctx, "a.example") {
+			t.Fatal("a.example reported blocked before it was added")
+		}
+	}
+	if got := server.lookups.Load(); got != 1 {
+		t.Errorf("HEXISTS sent %d times for repeated lookups, want 1", got)
+	}
+
+	// Hosts blocked by this scanner apply immediately despite the cached miss
+	blocklist.AddHost("a.example")
+	if !blocklist.IsBlocked(ctx, "a.example") {
+		t.Error("a.example not blocked after AddHost")
+	}
+	if got := blocklist.GetBlockedCount(); got != 1 {
+		t.Errorf("GetBlockedCount = %d, want 1", got)
+	}
+}
+
+func TestRedisBlocklistSkipsUnavailableRedis(t *testing.T) {
+	server := startFakeRedis(t)
+	blocklist, err := NewRedisBlocklist(server.url(), logging.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blocklist.Close()
+
+	server.down.Store(true)
+	ctx := context.Background()
+	if blocklist.IsBlocked(ctx, "first.example") {
+		t.Fatal("host reported blocked while Redis is down")
+	}
+	failedLookups := server.lookups.Load()
+	if failedLookups == 0 {
+		t.Fatal("first lookup did not reach Redis")
+	}
+
+	// Later lookups must not wait for the failing server
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			blocklist.IsBlocked(ctx, fmt.Sprintf("host%d.example", i))
+		}(i)
+	}
+	wg.Wait()
+
+	if got := server.lookups.Load(); got != failedLookups {
+		t.Errorf("HEXISTS sent %d more times after Redis failed, want 0", got-failedLookups)
+	}
+
+	// Blocks still apply locally
+	blocklist.AddHost("blocked.example")
+	if !blocklist.IsBlocked(ctx, "blocked.example") {
+		t.Error("blocked.example not blocked after AddHost while Redis is down")
+	}
+}
+
+func TestRedisBlocklistIgnoresStoppedScan(t *testing.T) {
+	server := startFakeRedis(t)
+	blocklist, err := NewRedisBlocklist(server.url(), logging.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blocklist.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	blocklist.IsBlocked(ctx, "a.example")
+
+	if !blocklist.available() {
+		t.Error("a cancelled lookup marked Redis unavailable")
+	}
+}
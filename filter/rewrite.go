@@ -0,0 +1,37 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+
+	"censei/config"
+)
+
+// URLRewriter applies find/replace regex rules to found-file URLs
+type URLRewriter struct {
+	patterns     []*regexp.Regexp
+	replacements []string
+}
+
+// NewURLRewriter compiles rewrite rules, which are applied in order
+// Replacements may reference capture groups ($1, ${name})
+func NewURLRewriter(rules []config.URLRewrite) (*URLRewriter, error) {
+	r := &URLRewriter{}
+	for i, rule := range rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url_rewrites pattern #%d %q: %w", i+1, rule.Pattern, err)
+		}
+		r.patterns = append(r.patterns, pattern)
+		r.replacements = append(r.replacements, rule.Replace)
+	}
+	return r, nil
+}
+
+// Rewrite returns fileURL with every rule applied, each to the result of the previous one
+func (r *URLRewriter) Rewrite(fileURL string) string {
+	for i, pattern := range r.patterns {
+		fileURL = pattern.ReplaceAllString(fileURL, r.replacements[i])
+	}
+	return fileURL
+}
@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"censei/logging"
+)
+
+// scanStateFlushInterval is the number of completed hosts after which the state file is flushed
+const scanStateFlushInterval = 10
+
+// ScanState records the URLs of hosts that were completely processed so an interrupted
+// scan can be resumed. Hosts are matched by exact URL only.
+type ScanState struct {
+	hosts    map[string]bool
+	loaded   int
+	pending  int // Hosts written since the last flush
+	file     *os.File
+	writer   *bufio.Writer
+	filePath string
+	logger   *logging.Logger
+	mu       sync.Mutex
+}
+
+// NewScanState opens the scan state file at filePath
+// With resume, the hosts recorded by a previous run are loaded and new hosts appended,
+// otherwise the file is started fresh.
+func NewScanState(filePath string, resume bool, logger *logging.Logger) (*ScanState, error) {
+	s := &ScanState{
+		hosts:    make(map[string]bool),
+		filePath: filePath,
+		logger:   logger,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create scan state directory: %w", err)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(filePath, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scan state file: %w", err)
+	}
+	s.file = file
+	s.writer = bufio.NewWriter(file)
+
+	return s, nil
+}
+
+// load reads the host URLs of a previous run (one per line)
+func (s *ScanState) load() error {
+	file, err := os.Open(s.filePath)
+	if os.IsNotExist(err) {
+		s.logger.Info("No scan state file found at %s, starting a new scan", s.filePath)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open scan state file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// A line cut short by a crash cannot match a host URL exactly and is ignored
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			s.hosts[line] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading scan state file: %w", err)
+	}
+
+	s.loaded = len(s.hosts)
+	s.logger.Info("Loaded %d processed hosts from scan state file %s", s.loaded, s.filePath)
+	return nil
+}
+
+// IsDone checks if a host URL was completely processed
+func (s *ScanState) IsDone(hostURL string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hosts[hostURL]
+}
+
+// MarkDone records a completely processed host URL
+// The file is flushed every scanStateFlushInterval hosts and on Close
+func (s *ScanState) MarkDone(hostURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.hosts[hostURL] {
+		return
+	}
+	s.hosts[hostURL] = true
+
+	if _, err := s.writer.WriteString(hostURL + "\n"); err != nil {
+		s.logger.Error("Failed to write scan state: %v", err)
+		return
+	}
+
+	s.pending++
+	if s.pending >= scanStateFlushInterval {
+		if err := s.writer.Flush(); err != nil {
+			s.logger.Error("Failed to save scan state: %v", err)
+		}
+		s.pending = 0
+	}
+}
+
+// GetLoadedCount returns the number of hosts loaded from a previous run
+func (s *ScanState) GetLoadedCount() int {
+	return s.loaded
+}
+
+// Close flushes and closes the scan state file
+func (s *ScanState) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flushErr := s.writer.Flush()
+	closeErr := s.file.Close()
+	if flushErr != nil {
+		return fmt.Errorf("failed to save scan state: %w", flushErr)
+	}
+	return closeErr
+}
@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"censei/logging"
+)
+
+// SkipList is a static list of hostnames, IPs and CIDR ranges that are never scanned
+// Unlike the Blocklist it is not earned during a run but maintained by the operator
+type SkipList struct {
+	hosts    map[string]bool
+	networks []*net.IPNet
+	logger   *logging.Logger
+}
+
+// NewSkipList creates an empty skip list
+func NewSkipList(logger *logging.Logger) *SkipList {
+	return &SkipList{
+		hosts:  make(map[string]bool),
+		logger: logger,
+	}
+}
+
+// Load reads entries from a file (one hostname, IP or CIDR per line, # for comments)
+func (s *SkipList) Load(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open skip hosts file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue // Skip empty lines and comments
+		}
+
+		// Only the first field is used, allowing trailing notes
+		entry := strings.ToLower(strings.Fields(line)[0])
+
+		if network := parseNetwork(entry); network != nil {
+			s.networks = append(s.networks, network)
+			continue
+		}
+		s.hosts[entry] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading skip hosts file: %w", err)
+	}
+
+	s.logger.Info("Loaded %d hosts and %d networks from skip hosts file %s", len(s.hosts), len(s.networks), filePath)
+	return nil
+}
+
+// IsSkipped checks if any of the given hostnames or IPs is on the skip list
+func (s *SkipList) IsSkipped(hostnames ...string) bool {
+	for _, hostname := range hostnames {
+		if hostname == "" {
+			continue
+		}
+
+		hostname = strings.ToLower(hostname)
+		if s.hosts[hostname] || containsIP(s.networks, hostname) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,10 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,6 +15,23 @@ type Logger struct {
 	logFile  *os.File
 	mu       sync.Mutex
 	fileName string
+	json     bool // Emit lines as JSON objects instead of text
+
+	// Size-based rotation (maxSize 0 = disabled), see SetRotation
+	maxSize    int64
+	maxBackups int
+	size       int64 // Bytes written to the current log file
+
+	// Credentials masked in every line, see AddSecrets
+	secrets  []string
+	redactor *strings.Replacer
+}
+
+// jsonLine is a log line in the JSON format
+type jsonLine struct {
+	TS    string `json:"ts"`
+	Level string `json:"level"`
+	Msg   string `json:"msg"`
 }
 
 // NewLogger creates a new logger with default settings
@@ -33,6 +52,22 @@ func (l *Logger) SetLevel(levelStr string) {
 	l.level = level
 }
 
+// SetFormat sets the line format from a string ("text" or "json")
+func (l *Logger) SetFormat(format string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch format {
+	case "", "text":
+		l.json = false
+	case "json":
+		l.json = true
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid log format: %s, using text\n", format)
+		l.json = false
+	}
+}
+
 // SetOutputFile sets the output file for logs
 func (l *Logger) SetOutputFile(fileName string) error {
 	l.mu.Lock()
@@ -44,6 +79,12 @@ func (l *Logger) SetOutputFile(fileName string) error {
 		l.logFile = nil
 	}
 
+	// With rotation, the log of the previous run becomes the first backup
+	l.fileName = fileName
+	if l.maxSize > 0 {
+		return l.rotate()
+	}
+
 	// Open new log file
 	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
@@ -51,7 +92,7 @@ func (l *Logger) SetOutputFile(fileName string) error {
 	}
 
 	l.logFile = file
-	l.fileName = fileName
+	l.size = 0
 	return nil
 }
 
@@ -64,23 +105,45 @@ func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	// Format the log message
-	// Optimize: Use single fmt.Sprintf with pre-allocated slice to avoid allocation in hot path
-	now := time.Now().Format("2006-01-02 15:04:05")
-	levelName := level.String()
-
-	// Pre-allocate slice with known capacity to avoid allocation on every log call
-	allArgs := make([]interface{}, 0, len(args)+2)
-	allArgs = append(allArgs, now, levelName)
-	allArgs = append(allArgs, args...)
-	logLine := fmt.Sprintf("[%s] %s "+format+"\n", allArgs...)
+	var logLine string
+	if l.json {
+		// One JSON object per line for log shippers (ELK, Loki)
+		line, err := json.Marshal(jsonLine{
+			TS:    time.Now().Format(time.RFC3339Nano),
+			Level: level.String(),
+			Msg:   l.redact(fmt.Sprintf(format, args...)),
+		})
+		if err != nil {
+			return
+		}
+		logLine = string(line) + "\n"
+	} else {
+		// Format the log message
+		// Optimize: Use single fmt.Sprintf with pre-allocated slice to avoid allocation in hot path
+		now := time.Now().Format("2006-01-02 15:04:05")
+		levelName := level.String()
+
+		// Pre-allocate slice with known capacity to avoid allocation on every log call
+		allArgs := make([]interface{}, 0, len(args)+2)
+		allArgs = append(allArgs, now, levelName)
+		allArgs = append(allArgs, args...)
+		logLine = l.redact(fmt.Sprintf("[%s] %s "+format+"\n", allArgs...))
+	}
 
 	// Write to console
 	fmt.Print(logLine)
 
-	// Write to file if configured
+	// Write to file if configured, rotating it first if the line would exceed the size limit
 	if l.logFile != nil {
-		l.logFile.WriteString(logLine)
+		if l.maxSize > 0 && l.size > 0 && l.size+int64(len(logLine)) > l.maxSize {
+			if err := l.rotate(); err != nil {
+				fmt.Fprintf(os.Stderr, "Log rotation failed: %v\n", err)
+			}
+		}
+		if l.logFile != nil {
+			n, _ := l.logFile.WriteString(logLine)
+			l.size += int64(n)
+		}
 	}
 }
 
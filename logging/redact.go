@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"sort"
+	"strings"
+)
+
+// redactedValue replaces credentials in log lines
+const redactedValue = "***"
+
+// credentialFlags are command line flags whose values are credentials
+var credentialFlags = map[string]bool{
+	"--api-id":     true,
+	"--api-secret": true,
+}
+
+// AddSecrets registers credential values (API ID, API secret, bearer token) that are
+// replaced with *** in every log line, so debug logs can be shared safely
+// Empty values are ignored
+func (l *Logger) AddSecrets(secrets ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, secret := range secrets {
+		if secret != "" {
+			l.secrets = append(l.secrets, secret)
+		}
+	}
+
+	// Longer secrets first, so a secret containing another one is replaced as a whole
+	pairs := make([]string, 0, 2*len(l.secrets))
+	sorted := make([]string, len(l.secrets))
+	copy(sorted, l.secrets)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for _, secret := range sorted {
+		pairs = append(pairs, secret, redactedValue)
+	}
+	l.redactor = strings.NewReplacer(pairs...)
+}
+
+// redact replaces registered secrets in a log message
+// Must be called with l.mu held
+func (l *Logger) redact(message string) string {
+	if l.redactor == nil {
+		return message
+	}
+	return l.redactor.Replace(message)
+}
+
+// RedactArgs returns a copy of a command argument vector with credential flag values replaced by ***
+// Handles both "--api-secret value" and "--api-secret=value"
+func RedactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+
+	for i := 0; i < len(redacted); i++ {
+		flag, _, hasValue := strings.Cut(redacted[i], "=")
+		if !credentialFlags[flag] {
+			continue
+		}
+		if hasValue {
+			redacted[i] = flag + "=" + redactedValue
+		} else if i+1 < len(redacted) {
+			redacted[i+1] = redactedValue
+			i++
+		}
+	}
+	return redacted
+}
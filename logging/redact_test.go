@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoggerRedactsCredentials(t *testing.T) {
+	const (
+		apiID       = "3f1c2a9e-api-id"
+		apiSecret   = "s3cr3t-Api-Secret"
+		bearerToken = "censys_bearer_0123456789"
+	)
+
+	// Legacy CLI command vector as built by CensysClient.ExecuteQuery
+	legacyArgs := []string{
+		"search",
+		"--api-id", apiID,
+		"--api-secret", apiSecret,
+		"--output", "censys_results.json",
+		"services.port: 80",
+	}
+
+	// Platform API v3 request carrying the bearer token
+	v3Request, err := http.NewRequest("POST", "https://api.platform.censys.io/v3/global/search/query", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v3Request.Header.Set("Authorization", "Bearer "+bearerToken)
+
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "censei.log")
+
+			logger := NewLogger()
+			logger.SetLevel("DEBUG")
+			logger.SetFormat(format)
+			if err := logger.SetOutputFile(logFile); err != nil {
+				t.Fatal(err)
+			}
+			logger.AddSecrets(apiID, apiSecret, bearerToken)
+
+			logger.Debug("Censys command: censys %s", strings.Join(RedactArgs(legacyArgs), " "))
+			logger.Debug("Censys command (unredacted args): censys %s", strings.Join(legacyArgs, " "))
+			logger.Debug("Platform API v3 request: %s %s %v", v3Request.Method, v3Request.URL, v3Request.Header)
+			logger.Error("Request failed with token %s", bearerToken)
+
+			data, err := os.ReadFile(logFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			logged := string(data)
+
+			for _, secret := range []string{apiID, apiSecret, bearerToken} {
+				if strings.Contains(logged, secret) {
+					t.Errorf("log output contains secret %q:\n%s", secret, logged)
+				}
+			}
+			for _, want := range []string{"--api-id ***", "--api-secret ***", "Bearer ***"} {
+				if !strings.Contains(logged, want) {
+					t.Errorf("log output missing %q:\n%s", want, logged)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "separate values",
+			args: []string{"search", "--api-id", "id", "--api-secret", "secret", "query"},
+			want: []string{"search", "--api-id", "***", "--api-secret", "***", "query"},
+		},
+		{
+			name: "inline values",
+			args: []string{"search", "--api-id=id", "--api-secret=secret", "query"},
+			want: []string{"search", "--api-id=***", "--api-secret=***", "query"},
+		},
+		{
+			name: "trailing flag without value",
+			args: []string{"search", "--api-secret"},
+			want: []string{"search", "--api-secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := strings.Join(tt.args, " ")
+			got := RedactArgs(tt.args)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("RedactArgs() = %q, want %q", got, tt.want)
+			}
+			if strings.Join(tt.args, " ") != original {
+				t.Errorf("RedactArgs() modified its input: %q", tt.args)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+)
+
+// defaultMaxBackups is the number of rotated log files kept when none is configured
+const defaultMaxBackups = 3
+
+// SetRotation enables size-based rotation of the log file
+// Once the file would exceed maxSizeMB, it is renamed to <file>.1 (older backups shift
+// to .2, .3, ...) and a new file is started. At most maxBackups files are kept (0 = 3).
+// A maxSizeMB of 0 disables rotation. Call before SetOutputFile so the log of the
+// previous run is kept as a backup instead of being truncated.
+func (l *Logger) SetRotation(maxSizeMB int, maxBackups int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxBackups
+	}
+	l.maxSize = int64(maxSizeMB) * 1024 * 1024
+	l.maxBackups = maxBackups
+}
+
+// rotate shifts the backups, renames the current log file to <file>.1 and opens a new one
+// If the backups cannot be shifted, logging continues in the current file
+// Must be called with l.mu held
+func (l *Logger) rotate() error {
+	if l.logFile != nil {
+		l.logFile.Close()
+		l.logFile = nil
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	shiftErr := l.shiftBackups()
+	if shiftErr != nil {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(l.fileName, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	l.logFile = file
+	l.size = 0
+	return shiftErr
+}
+
+// shiftBackups renames <file>.N-1 to <file>.N down to <file> itself, dropping the oldest backup
+// Does nothing if the log file does not exist or is empty
+func (l *Logger) shiftBackups() error {
+	info, err := os.Stat(l.fileName)
+	if err != nil || info.Size() == 0 {
+		return nil
+	}
+
+	oldest := fmt.Sprintf("%s.%d", l.fileName, l.maxBackups)
+	if err := os.Remove(oldest); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove old log file: %w", err)
+	}
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		backup := fmt.Sprintf("%s.%d", l.fileName, i)
+		if err := os.Rename(backup, fmt.Sprintf("%s.%d", l.fileName, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	if err := os.Rename(l.fileName, l.fileName+".1"); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
 	"time"
 
 	"censei/api"
@@ -14,7 +22,11 @@ import (
 	"censei/filechecker"
 	"censei/filter"
 	"censei/logging"
+	"censei/metrics"
+	"censei/netproxy"
+	"censei/notify"
 	"censei/output"
+	"censei/useragent"
 )
 
 // checkCensysCLI checks if the censys-cli tool is available
@@ -41,7 +53,7 @@ func main() {
 	configPath := flag.String("config", "./config.json", "Path to config file")
 	queriesPath := flag.String("queries", "", "Path to queries file (overrides default)")
 	filterStr := flag.String("filter", "", "Custom file extensions to filter (comma-separated, e.g. .pdf,.exe)")
-	queryStr := flag.String("query", "", "Run specific query directly")
+	queryStr := flag.String("query", "", "Run specific query directly (- reads the query from stdin)")
 	outputPath := flag.String("output", "", "Override output directory")
 	logLevel := flag.String("log-level", "", "Override log level (DEBUG, INFO, ERROR)")
 	checkFlag := flag.Bool("check", false, "Enable targeted file checking mode - skips HTML processing and link extraction, directly checks hosts for specific binary files")
@@ -49,11 +61,35 @@ func main() {
 	recursiveFlag := flag.Bool("recursive", false, "Enable recursive directory scanning")
 	maxDepthFlag := flag.Int("max-depth", 1, "Maximum depth for recursive scanning")
 	legacyFlag := flag.Bool("legacy", false, "Use legacy CLI-based Censys API instead of Platform API v3")
+	outputFormat := flag.String("output-format", "", "Additional output format (httpx: httpx-compatible JSON Lines, markdown: report.md)")
+	resumeFlag := flag.Bool("resume", false, "Resume an interrupted scan, skipping hosts recorded in scan_state.txt")
+	allFlag := flag.Bool("all", false, "Run every query in the queries file in sequence without the interactive menu")
+	validateQueriesPath := flag.String("validate-queries", "", "Validate a queries file and exit without scanning")
 	flag.Parse()
 
+	// Validation mode needs neither credentials nor network
+	if *validateQueriesPath != "" {
+		os.Exit(validateQueriesFile(*validateQueriesPath))
+	}
+
 	// Initialize logging system
 	logger := logging.NewLogger()
 
+	if *allFlag && *queryStr != "" {
+		logger.Error("-all and -query cannot be combined")
+		os.Exit(1)
+	}
+
+	// -query - reads the query from a pipe, e.g. echo 'labels:open-dir' | censei -query -
+	if *queryStr == "-" {
+		query, err := readQueryFromStdin()
+		if err != nil {
+			logger.Error("Failed to read query from stdin: %v", err)
+			os.Exit(1)
+		}
+		*queryStr = query
+	}
+
 	// Load configuration first to get query file paths
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
@@ -61,6 +97,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Never write credentials to the console or log file, users share debug logs in issues
+	logger.AddSecrets(cfg.APIKey, cfg.APISecret, cfg.BearerToken)
+
+	// The chat format is detected when the notification is sent, warn before a long scan
+	if cfg.NotifyOnComplete && cfg.NotifyFormat == "" && notify.DetectFormat(cfg.NotifyWebhookURL) == "" {
+		logger.Error("WARNING: notify_webhook_url is not a Slack or Discord webhook URL, set notify_format to \"slack\" or \"discord\" - the completion notification will fail")
+	}
+
 	// Determine which queries file to use
 	var finalQueriesPath string
 	if *queriesPath != "" {
@@ -111,14 +155,49 @@ func main() {
 	if *logLevel != "" {
 		cfg.LogLevel = *logLevel
 	}
+	if *outputFormat != "" {
+		cfg.OutputFormat = *outputFormat
+	}
+	if cfg.OutputFormat != "" && cfg.OutputFormat != "httpx" && cfg.OutputFormat != "markdown" {
+		logger.Error("Unsupported output format: %s (supported: httpx, markdown)", cfg.OutputFormat)
+		os.Exit(1)
+	}
+
+	// Fail fast before the Censys query spends credits on results that could not be saved
+	if err := checkOutputWritable(cfg.OutputDir); err != nil {
+		logger.Error("Output directory %s is not writable: %v", cfg.OutputDir, err)
+		os.Exit(1)
+	}
 
-	// Apply log level from config
+	// Apply log level and format from config
 	logger.SetLevel(cfg.LogLevel)
+	logger.SetFormat(cfg.LogFormat)
+	logger.SetRotation(cfg.LogMaxSizeMB, cfg.LogMaxBackups)
 	logger.SetOutputFile(cfg.LogFile)
 
 	// Initialize the application
 	logger.Info("Censei Scanner starting up...")
 
+	// Generate a run ID to correlate logs and output files of this invocation
+	runID := output.NewRunID(time.Now())
+	logger.Info("Run ID: %s", runID)
+
+	// Record completed hosts so an interrupted scan can be resumed with -resume
+	scanState, err := filter.NewScanState(filepath.Join(cfg.OutputDir, "scan_state.txt"), *resumeFlag, logger)
+	if err != nil {
+		logger.Error("Failed to open scan state: %v", err)
+		os.Exit(1)
+	}
+	closeScanState := func() {
+		if err := scanState.Close(); err != nil {
+			logger.Error("Failed to close scan state: %v", err)
+		}
+	}
+	defer closeScanState()
+	if *resumeFlag {
+		logger.Info("Resuming scan - %d hosts from the previous run will be skipped", scanState.GetLoadedCount())
+	}
+
 	// Load queries configuration with helpful error messages
 	queries, err := config.LoadQueries(finalQueriesPath)
 	if err != nil {
@@ -145,6 +224,9 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Queries that could not be run, the exit status is 1 if there are any
+	failedQueries := 0
+
 	// If a direct query is provided, run it
 	if *queryStr != "" {
 		logger.Info("Running direct query: %s", *queryStr)
@@ -166,56 +248,176 @@ func main() {
 			MaxDepth:       *maxDepthFlag,
 		}
 
-		runQueryConfig(cfg, queryConfig, logger, *legacyFlag)
+		if _, err := runQueryConfig(cfg, queryConfig, runID, scanState, logger, *legacyFlag, make(map[string]bool)); err != nil {
+			logger.Error("Query failed: %v", err)
+			failedQueries++
+		}
+	} else if *allFlag {
+		// Batch mode for cron jobs and CI: every query of the file, no menu
+		logger.Info("Batch mode: running all %d queries from %s", len(queries), finalQueriesPath)
+
+		// Keep the results of the queries apart unless output_mode already does
+		if cfg.OutputMode == "" || cfg.OutputMode == output.OutputModeOverwrite {
+			logger.Info("Writing output files per query (output_mode %s)", output.OutputModePrefixed)
+			cfg.OutputMode = output.OutputModePrefixed
+		}
+
+		queryConfigs := make([]*config.Query, 0, len(queries))
+		for i := range queries {
+			queryConfig := queries[i]
+			// Command line flags override the settings of every query, as in interactive mode
+			if *filterStr != "" {
+				queryConfig.Filters = cli.ParseFilters(*filterStr)
+			}
+			if *checkFlag {
+				queryConfig.Check = true
+			}
+			if *targetFile != "" {
+				queryConfig.TargetFileName = *targetFile
+			}
+			if *recursiveFlag {
+				queryConfig.Recursive = "yes"
+			}
+			if *maxDepthFlag > 1 {
+				queryConfig.MaxDepth = *maxDepthFlag
+			}
+			queryConfigs = append(queryConfigs, &queryConfig)
+		}
+
+		failedQueries = runQuerySequence(cfg, queryConfigs, runID, scanState, logger, *legacyFlag)
 	} else {
 		// Start interactive mode
-		selectedQuery, selectedFilters, checkEnabled, targetFileName := cli.ShowMenuWithCheck(
-			queries, *filterStr, *checkFlag, *targetFile, *legacyFlag)
-		if selectedQuery == "" {
+		selections := cli.ShowMenuMulti(queries, *filterStr, *checkFlag, *targetFile, *legacyFlag)
+		if len(selections) == 0 {
 			logger.Error("No query selected, exiting")
 			os.Exit(0)
 		}
 
-		// Find the selected query config
-		var queryConfig *config.Query
-		for _, q := range queries {
-			if q.Query == selectedQuery {
-				queryConfig = &q
-				// Override with command line parameters if provided
-				if *filterStr != "" {
-					queryConfig.Filters = selectedFilters
-				}
-				if *checkFlag {
-					queryConfig.Check = checkEnabled
-				}
-				if *targetFile != "" {
-					queryConfig.TargetFileName = targetFileName
-				}
-				if *recursiveFlag {
-					queryConfig.Recursive = "yes"
+		// Build the query configs with the command line overrides applied
+		queryConfigs := make([]*config.Query, 0, len(selections))
+		for _, selection := range selections {
+			selectedQuery := selection.Query
+			selectedFilters := selection.Filters
+			checkEnabled := selection.Check
+			targetFileName := selection.TargetFileName
+
+			// Find the selected query config
+			var queryConfig *config.Query
+			for _, q := range queries {
+				if q.Query == selectedQuery {
+					queryConfig = &q
+					// Override with command line parameters if provided
+					if *filterStr != "" {
+						queryConfig.Filters = selectedFilters
+					}
+					if *checkFlag {
+						queryConfig.Check = checkEnabled
+					}
+					if *targetFile != "" {
+						queryConfig.TargetFileName = targetFileName
+					}
+					if *recursiveFlag {
+						queryConfig.Recursive = "yes"
+					}
+					if *maxDepthFlag > 1 {
+						queryConfig.MaxDepth = *maxDepthFlag
+					}
+					break
 				}
-				if *maxDepthFlag > 1 {
-					queryConfig.MaxDepth = *maxDepthFlag
+			}
+
+			// If no predefined query found, create custom query
+			if queryConfig == nil {
+				queryConfig = &config.Query{
+					Name:           "Custom Query",
+					Query:          selectedQuery,
+					Filters:        selectedFilters,
+					Check:          checkEnabled,
+					TargetFileName: targetFileName,
+					Recursive:      boolToYesNo(*recursiveFlag),
+					MaxDepth:       *maxDepthFlag,
 				}
-				break
 			}
+			queryConfigs = append(queryConfigs, queryConfig)
 		}
 
-		// If no predefined query found, create custom query
-		if queryConfig == nil {
-			queryConfig = &config.Query{
-				Name:           "Custom Query",
-				Query:          selectedQuery,
-				Filters:        selectedFilters,
-				Check:          checkEnabled,
-				TargetFileName: targetFileName,
-				Recursive:      boolToYesNo(*recursiveFlag),
-				MaxDepth:       *maxDepthFlag,
-			}
+		// Several selected queries run in sequence and write combined output
+		failedQueries = runQuerySequence(cfg, queryConfigs, runID, scanState, logger, *legacyFlag)
+	}
+
+	// Exit with status 1 for cron jobs and CI if a query failed; os.Exit skips the deferred close
+	if failedQueries > 0 {
+		closeScanState()
+		os.Exit(1)
+	}
+}
+
+// runQuerySequence runs queries one after another, followed by an aggregate summary if there
+// are several; queries sharing an output location append to the same output files
+// A query that fails is logged and the next one is run; an interruption (Ctrl+C) skips the remaining queries
+// Returns the number of failed queries
+func runQuerySequence(cfg *config.Config, queryConfigs []*config.Query, runID string, scanState *filter.ScanState, logger *logging.Logger, useLegacy bool) int {
+	runStart := time.Now()
+	writtenOutputs := make(map[string]bool)
+	var totals []output.QueryTotals
+	failed := 0
+	for i, queryConfig := range queryConfigs {
+		if len(queryConfigs) > 1 {
+			logger.Info("Running query %d of %d: %s", i+1, len(queryConfigs), queryConfig.Name)
+		}
+		queryTotals, err := runQueryConfig(cfg, queryConfig, runID, scanState, logger, useLegacy, writtenOutputs)
+		if err != nil {
+			logger.Error("Query %q failed: %v", queryConfig.Name, err)
+			failed++
+			queryTotals = output.QueryTotals{Name: queryConfig.Name, Failed: true}
+		}
+		totals = append(totals, queryTotals)
+
+		// Ctrl+C stops the whole sequence, not just the current query
+		if queryTotals.Interrupted && i < len(queryConfigs)-1 {
+			logger.Info("Skipping the remaining %d queries after the interruption", len(queryConfigs)-1-i)
+			break
+		}
+	}
+
+	if len(queryConfigs) > 1 {
+		logger.Info("\n%s", output.FormatAggregateSummary(runID, totals, runStart, time.Now()))
+	}
+	return failed
+}
+
+// validateQueriesFile prints a pass/fail report for every query in a queries file
+// Returns the exit code: 0 if all queries are valid, 1 otherwise
+func validateQueriesFile(path string) int {
+	results, err := config.ValidateQueriesFile(path)
+	if err != nil {
+		fmt.Printf("FAIL  %s: %v\n", path, err)
+		return 1
+	}
+
+	failed := 0
+	for i, result := range results {
+		name := result.Query.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+
+		if len(result.Problems) == 0 {
+			fmt.Printf("PASS  #%d %s\n", i+1, name)
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL  #%d %s\n", i+1, name)
+		for _, problem := range result.Problems {
+			fmt.Printf("        - %s\n", problem)
 		}
+	}
 
-		runQueryConfig(cfg, queryConfig, logger, *legacyFlag)
+	fmt.Printf("\n%d of %d queries valid in %s\n", len(results)-failed, len(results), path)
+	if failed > 0 {
+		return 1
 	}
+	return 0
 }
 
 // boolToYesNo converts a boolean to "yes"/"no" string
@@ -226,10 +428,186 @@ func boolToYesNo(b bool) string {
 	return "no"
 }
 
+// startIdleReaper closes idle connections on every interval until the returned stop function is called
+func startIdleReaper(interval time.Duration, closers []func(), logger *logging.Logger) func() {
+	logger.Info("Closing idle connections every %v", interval)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				logger.Debug("Closing idle connections")
+				for _, closeIdle := range closers {
+					closeIdle()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// readQueryFromStdin reads a query piped to stdin; lines are joined with spaces
+// Fails instead of waiting for input when stdin is a terminal
+func readQueryFromStdin() (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeCharDevice != 0 {
+		return "", fmt.Errorf("stdin is a terminal, pipe the query (echo '<query>' | censei -query -) or pass it with -query")
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("no query received on stdin")
+	}
+	return strings.Join(lines, " "), nil
+}
+
+// checkOutputWritable creates the output directory if needed and writes and removes a test file in it
+func checkOutputWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, ".censei-write-check-*")
+	if err != nil {
+		return err
+	}
+	name := file.Name()
+	if _, err := file.WriteString("censei\n"); err != nil {
+		file.Close()
+		os.Remove(name)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(name)
+		return err
+	}
+	return os.Remove(name)
+}
+
+// startHeartbeat logs the scan statistics at every interval until the returned stop function is called
+// Unlike the progress log every 10 hosts, it shows the scan is alive while slow hosts time out
+func startHeartbeat(interval time.Duration, worker *crawler.Worker, logger *logging.Logger) func() {
+	startTime := time.Now()
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				totalHosts, onlineHosts, totalFiles, filteredFiles, _, binaryFilesFound, _, _ := worker.GetStats()
+				logger.Info("Heartbeat: %d/%d hosts processed, %d in progress, %d online, %d files found, %d filtered, %d binaries (running %s)",
+					worker.GetProcessedHosts(), totalHosts, worker.GetActiveWorkers(), onlineHosts, totalFiles, filteredFiles, binaryFilesFound,
+					time.Since(startTime).Round(time.Second))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// prepareHosts applies the configured host filters, expansions (bare IPs, virtual hosts) and priority
+// Returns the resulting hosts and the number of IP-based hosts that were added
+func prepareHosts(cfg *config.Config, hosts []api.Host, logger *logging.Logger) ([]api.Host, int) {
+	// Optionally restrict the scan to IPv4 or IPv6 hosts
+	scanIPv4 := cfg.ScanIPv4 == nil || *cfg.ScanIPv4
+	scanIPv6 := cfg.ScanIPv6 == nil || *cfg.ScanIPv6
+	hosts, dropped := api.FilterHostsByIPVersion(hosts, scanIPv4, scanIPv6)
+	if dropped > 0 {
+		logger.Info("Dropped %d hosts by address family (scan_ipv4: %t, scan_ipv6: %t)", dropped, scanIPv4, scanIPv6)
+	}
+
+	// Optionally never scan internal addresses (safety guard against bad queries)
+	if cfg.SkipPrivateIPs {
+		var privateHosts []api.Host
+		hosts, privateHosts = api.FilterPrivateHosts(hosts)
+		for _, host := range privateHosts {
+			logger.Debug("Skipping host with private IP %s: %s", host.IP, host.URL)
+		}
+		if len(privateHosts) > 0 {
+			logger.Info("Dropped %d hosts with private, loopback or link-local IPs (skip_private_ips)", len(privateHosts))
+		}
+	}
+
+	// Optionally restrict the scan to hosts following a naming pattern
+	if cfg.HostNameRegex != "" {
+		hosts, dropped = api.FilterHostsByName(hosts, regexp.MustCompile(cfg.HostNameRegex))
+		if dropped > 0 {
+			logger.Info("Dropped %d hosts not matching host_name_regex %s", dropped, cfg.HostNameRegex)
+		}
+	}
+
+	// Optionally restrict the scan to allowed ports and skip blocked ones
+	hosts, dropped = api.FilterHostsByPort(hosts, cfg.AllowedPorts, cfg.BlockedPorts)
+	if dropped > 0 {
+		logger.Info("Dropped %d hosts by port (allowed_ports: %v, blocked_ports: %v)", dropped, cfg.AllowedPorts, cfg.BlockedPorts)
+	}
+
+	// Optionally add IP-based variants of hosts that were resolved to DNS names
+	extraIPHosts := 0
+	if cfg.AlsoScanIP {
+		hosts, extraIPHosts = api.AddIPHosts(hosts)
+		logger.Info("Added %d IP-based hosts for DNS-resolved hosts", extraIPHosts)
+	}
+
+	// Optionally send a specific Host header / TLS SNI when connecting by IP
+	if cfg.VirtualHost != "" || cfg.ReverseDNSVirtualHost {
+		applied := api.ApplyVirtualHosts(hosts, cfg.VirtualHost, cfg.ReverseDNSVirtualHost)
+		logger.Info("Using virtual host (Host header / SNI) for %d IP-based hosts", applied)
+	}
+
+	// Optionally scan promising hosts first so a truncated scan covers them
+	if len(cfg.HostPriority) > 0 {
+		prioritizer, err := api.NewHostPrioritizer(cfg.HostPriority)
+		if err != nil {
+			logger.Error("Failed to set up host priority: %v", err)
+			os.Exit(1)
+		}
+		scored := prioritizer.Sort(hosts)
+		logger.Info("Prioritized %d of %d hosts by host_priority rules", scored, len(hosts))
+	}
+
+	return hosts, extraIPHosts
+}
+
 // runQueryConfig runs a query using a complete Query configuration object
-func runQueryConfig(cfg *config.Config, queryConfig *config.Query, logger *logging.Logger, useLegacy bool) {
+// writtenOutputs records the output locations of earlier queries of this run; a query writing to
+// the same location appends to their files instead of replacing them
+// Returns the key counts of the query for the aggregate summary of multi-query runs, or an error
+// if the query could not be run; output opened so far is closed before returning
+func runQueryConfig(cfg *config.Config, queryConfig *config.Query, runID string, scanState *filter.ScanState, logger *logging.Logger, useLegacy bool, writtenOutputs map[string]bool) (totals output.QueryTotals, err error) {
 	startTime := time.Now()
 
+	// Ctrl+C / SIGTERM stop the scan gracefully so partial results are saved
+	ctx, stopShutdownHandler := shutdownContext(logger)
+	defer stopShutdownHandler()
+
 	// Initialize statistics
 	stats := struct {
 		totalHosts       int
@@ -239,6 +617,7 @@ func runQueryConfig(cfg *config.Config, queryConfig *config.Query, logger *loggi
 		checkedFiles     int
 		binaryFilesFound int
 		writeErrors      int
+		notListingHosts  int
 	}{
 		totalHosts:       0,
 		onlineHosts:      0,
@@ -247,6 +626,7 @@ func runQueryConfig(cfg *config.Config, queryConfig *config.Query, logger *loggi
 		checkedFiles:     0,
 		binaryFilesFound: 0,
 		writeErrors:      0,
+		notListingHosts:  0,
 	}
 
 	// Log query configuration
@@ -256,6 +636,11 @@ func runQueryConfig(cfg *config.Config, queryConfig *config.Query, logger *loggi
 		logger.Info("Max Depth: %d", queryConfig.MaxDepth)
 	}
 
+	// Reject malformed queries before they spend a round-trip and API quota
+	if err := api.ValidateQuery(queryConfig.Query); err != nil {
+		return output.QueryTotals{}, fmt.Errorf("invalid query %q: %w", queryConfig.Query, err)
+	}
+
 	// Log API mode
 	if useLegacy {
 		logger.Info("Using Legacy CLI-based API")
@@ -264,64 +649,215 @@ func runQueryConfig(cfg *config.Config, queryConfig *config.Query, logger *loggi
 	}
 
 	var hosts []api.Host
-	var err error
+	var jsonPath string // Intermediate censys_results.json, see finishRawResults
+
+	// Number of Censys results before expansion to service URLs
+	censysResults := 0
+
+	// Pipelined mode starts crawling hosts while later API pages are still being fetched
+	var pipelineClient *api.CensysV3Client
+	pipelined := cfg.PipelineCrawl && !useLegacy
+	if cfg.PipelineCrawl && useLegacy {
+		logger.Info("pipeline_crawl is not supported in legacy mode, fetching all results first")
+	}
 
 	if useLegacy {
 		// Legacy mode: Use CLI-based Censys client
 		censysClient := api.NewCensysClient(cfg.APIKey, cfg.APISecret, cfg, logger)
 
 		// Execute Censys query
-		jsonPath, err := censysClient.ExecuteQuery(queryConfig.Query, cfg.OutputDir)
+		jsonPath, err = censysClient.ExecuteQuery(queryConfig.Query, cfg.OutputDir)
 		if err != nil {
-			logger.Error("Failed to execute Censys query: %v", err)
-			os.Exit(1)
+			return output.QueryTotals{}, fmt.Errorf("failed to execute Censys query: %w", err)
 		}
 
 		// Extract hosts from results
 		hosts, err = censysClient.ExtractHostsFromResults(jsonPath)
 		if err != nil {
-			logger.Error("Failed to extract hosts from results: %v", err)
-			os.Exit(1)
+			return output.QueryTotals{}, fmt.Errorf("failed to extract hosts from results: %w", err)
 		}
+		censysResults = censysClient.ResultCount()
 	} else {
 		// Platform API v3 mode
 		censysV3Client, err := api.NewCensysV3Client(cfg.BearerToken, cfg, logger)
 		if err != nil {
-			logger.Error("Failed to initialize Platform API v3 client: %v", err)
-			os.Exit(1)
+			return output.QueryTotals{}, fmt.Errorf("failed to initialize Platform API v3 client: %w", err)
 		}
 
-		// Execute Censys query
-		jsonPath, err := censysV3Client.ExecuteQuery(queryConfig.Query, cfg.OutputDir)
-		if err != nil {
-			logger.Error("Failed to execute Platform API v3 query: %v", err)
-			os.Exit(1)
-		}
+		if pipelined {
+			// Query is executed later, feeding the worker page by page
+			pipelineClient = censysV3Client
+		} else {
+			// Execute Censys query
+			jsonPath, err = censysV3Client.ExecuteQuery(ctx, queryConfig.Query, cfg.OutputDir)
+			if err != nil {
+				return output.QueryTotals{}, fmt.Errorf("failed to execute Platform API v3 query: %w", err)
+			}
 
-		// Extract hosts from results
-		hosts, err = censysV3Client.ExtractHostsFromResults(jsonPath)
-		if err != nil {
-			logger.Error("Failed to extract hosts from Platform API v3 results: %v", err)
-			os.Exit(1)
+			// Extract hosts from results
+			hosts, err = censysV3Client.ExtractHostsFromResults(jsonPath)
+			if err != nil {
+				return output.QueryTotals{}, fmt.Errorf("failed to extract hosts from Platform API v3 results: %w", err)
+			}
+			censysResults = censysV3Client.ResultCount()
 		}
 	}
 
-	logger.Info("Extracted %d hosts from Censys results", len(hosts))
+	// The hosts were extracted, the intermediate results file is no longer needed
+	if jsonPath != "" {
+		finishRawResults(cfg, jsonPath, runID, logger)
+	}
+
+	extraIPHosts := 0
+	if !pipelined {
+		logger.Info("Extracted %d hosts from Censys results", len(hosts))
+		hosts, extraIPHosts = prepareHosts(cfg, hosts, logger)
+	}
 
 	// Initialize output writer
-	writer, err := output.NewWriter(cfg.OutputDir, logger)
+	// output_mode keeps consecutive runs apart via a timestamped subdirectory or a query name prefix
+	// Optionally prefix output filenames with the run ID so scans can share an output directory
+	outputDir, filePrefix := output.ResolveOutputLocation(cfg.OutputMode, cfg.OutputDir, queryConfig.Name, startTime)
+	if cfg.RunIDInFilenames {
+		filePrefix += runID + "_"
+	}
+	outputLocation := filepath.Join(outputDir, filePrefix)
+	writer, err := output.NewWriter(outputDir, filePrefix, writtenOutputs[outputLocation], logger)
 	if err != nil {
-		logger.Error("Failed to initialize output writer: %v", err)
-		os.Exit(1)
+		return output.QueryTotals{}, fmt.Errorf("failed to initialize output writer: %w", err)
 	}
+	writtenOutputs[outputLocation] = true
+
+	// post_scan_command runs once the writer is closed, so the output files are complete
+	// hookEnv is set when the summary is written; scans that exit early run no hook
+	var hookEnv []string
+	defer func() {
+		if len(cfg.PostScanCommand) > 0 && hookEnv != nil {
+			if hookErr := runPostScanCommand(cfg, hookEnv, logger); hookErr != nil {
+				logger.Error("post_scan_command failed: %v", hookErr)
+				if cfg.FailOnHookError && err == nil {
+					err = fmt.Errorf("post_scan_command failed: %w", hookErr)
+				}
+			}
+		}
+	}()
 	defer writer.Close()
 
+	// Optionally export discovered directory URLs
+	if cfg.ExportDirectories {
+		if err := writer.EnableDirectoryOutput(); err != nil {
+			return output.QueryTotals{}, fmt.Errorf("failed to enable directory output: %w", err)
+		}
+	}
+
+	// Optionally collect an inventory of all file extensions seen
+	if cfg.ExportExtensions {
+		writer.EnableExtensionOutput()
+	}
+
+	// Optionally write online hosts in httpx JSON Lines format
+	if cfg.OutputFormat == "httpx" {
+		if err := writer.EnableHTTPXOutput(); err != nil {
+			return output.QueryTotals{}, fmt.Errorf("failed to enable httpx output: %w", err)
+		}
+	}
+
+	// Optionally report only hosts serving binaries in raw.txt and filtered.txt
+	if cfg.BinariesOnly {
+		if !queryConfig.Check {
+			logger.Error("WARNING: binaries_only requires file checking (check) - raw.txt and filtered.txt will only contain the summary")
+		}
+		writer.EnableBinariesOnly()
+	}
+
+	// Optionally split found files into one file per category
+	if cfg.CategoryOutput {
+		writer.EnableCategoryOutput()
+	}
+
+	// Optionally write found files as paths to compare directory structures across hosts
+	if cfg.PathOnlyOutput {
+		writer.EnablePathOnlyOutput(cfg.PathOnlyIncludeHost)
+	}
+
+	// Optionally collect a structured JSON report (results.json)
+	// The Markdown report is built from the same collected data
+	if cfg.JSONOutput || cfg.OutputFormat == "markdown" {
+		writer.EnableJSONReport()
+	}
+
+	// Optionally record response headers of interest per host
+	if len(cfg.CaptureHeaders) > 0 {
+		if err := writer.EnableHeaderOutput(); err != nil {
+			return output.QueryTotals{}, fmt.Errorf("failed to enable header output: %w", err)
+		}
+	}
+
+	// Optionally record TLS certificate details per HTTPS host
+	if cfg.CaptureTLSInfo {
+		if err := writer.EnableCertificateOutput(); err != nil {
+			return output.QueryTotals{}, fmt.Errorf("failed to enable certificate output: %w", err)
+		}
+	}
+
+	// Optionally save confirmed binaries to disk
+	if cfg.DownloadBinaries {
+		if !queryConfig.Check {
+			logger.Error("WARNING: download_binaries requires file checking (check) - no files will be downloaded")
+		}
+		if err := writer.EnableDownloads(cfg.DownloadsDir); err != nil {
+			return output.QueryTotals{}, fmt.Errorf("failed to enable downloads: %w", err)
+		}
+	}
+
+	// Optionally report binary extensions served with a non-binary Content-Type
+	if cfg.ReportMismatches {
+		if !queryConfig.Check {
+			logger.Error("WARNING: report_mismatches requires file checking (check) - mismatches.txt will stay empty")
+		}
+		if err := writer.EnableMismatchOutput(); err != nil {
+			return output.QueryTotals{}, fmt.Errorf("failed to enable mismatch output: %w", err)
+		}
+	}
+
 	// Initialize filter
-	fileFilter := filter.NewFilter(queryConfig.Filters, logger)
-	logger.Info("Using filters: %v", fileFilter.GetFilterExtensions())
+	fileFilter := filter.NewFilter(queryConfig.Filters, queryConfig.FilterRegex, logger)
+	appliedFilters := append(fileFilter.GetFilterExtensions(), fileFilter.GetFilterPatterns()...)
+	logger.Info("Using filters: %v", appliedFilters)
 
 	// Initialize crawler components
 	client := crawler.NewClient(cfg.HTTPTimeoutSeconds, logger)
+	client.SetConnectionPool(cfg.MaxIdleConns, cfg.MaxIdleConnsPerHost)
+	client.SetHTTP2(cfg.EnableHTTP2)
+	client.SetMaxListingChunks(cfg.MaxListingChunks)
+	client.SetAcceptLanguage(cfg.AcceptLanguage)
+	client.SetCaptureHeaders(cfg.CaptureHeaders)
+	client.SetRedirects(cfg.FollowRedirects, cfg.MaxRedirects, cfg.AllowCrossHostRedirects)
+	client.SetThrottle(cfg.ThrottleRetries, cfg.ThrottlePauseSeconds)
+	if err := client.SetProxy(cfg.ProxyURL); err != nil {
+		return output.QueryTotals{}, fmt.Errorf("failed to configure proxy: %w", err)
+	}
+	if cfg.HostHeaderIncludePort != nil {
+		client.SetStripHostPort(!*cfg.HostHeaderIncludePort)
+	}
+
+	if cfg.ProxyURL != "" {
+		logger.Info("Routing crawl, file check, FTP and SMB connections through proxy %s", redactProxyURL(cfg.ProxyURL))
+	}
+
+	// Share one User-Agent picker between crawler and file checker
+	userAgents := useragent.NewPicker(cfg.UserAgent, cfg.UserAgentPool, cfg.UserAgentPerHost)
+	client.SetUserAgents(userAgents)
+	if len(cfg.UserAgentPool) > 0 {
+		logger.Info("Using User-Agent pool with %d entries", len(cfg.UserAgentPool))
+	}
+
+	// Webhooks share one client that goes through proxy_url like the scan
+	webhookTransport, err := netproxy.NewHTTPTransport(cfg.ProxyURL)
+	if err != nil {
+		return output.QueryTotals{}, fmt.Errorf("failed to configure proxy: %w", err)
+	}
+	poster := notify.NewPoster(webhookTransport)
 
 	// Initialize worker with query config
 	worker := crawler.NewWorker(
@@ -332,49 +868,231 @@ func runQueryConfig(cfg *config.Config, queryConfig *config.Query, logger *loggi
 		queryConfig,
 		cfg,
 		cfg.MaxConcurrentRequests,
+		poster,
 	)
+	worker.SetScanState(scanState)
+
+	// Idle connections are reaped from every transport used during the scan
+	idleClosers := []func(){client.CloseIdleConnections}
 
 	// Initialize file checker if enabled
-	if queryConfig.Check {
-		logger.Info("File checking functionality enabled, looking for binary files")
-		if queryConfig.TargetFileName != "" {
-			logger.Info("Target filename: %s", queryConfig.TargetFileName)
+	// Size filters reuse its client for HEAD requests without enabling content checks
+	if queryConfig.Check || cfg.MinFileSize > 0 || cfg.MaxFileSize > 0 {
+		if queryConfig.Check {
+			logger.Info("File checking functionality enabled, looking for binary files")
+			if queryConfig.TargetFileName != "" {
+				logger.Info("Target filename: %s", queryConfig.TargetFileName)
+			}
 		}
 
 		// Create file checker
-		fileChecker := filechecker.NewFileChecker(cfg.HTTPTimeoutSeconds, logger)
+		// File checks may use their own timeout, falling back to the crawler timeout
+		checkTimeout := cfg.HTTPTimeoutSeconds
+		if cfg.CheckTimeoutSeconds > 0 {
+			checkTimeout = cfg.CheckTimeoutSeconds
+		}
+		fileChecker := filechecker.NewFileChecker(checkTimeout, logger)
+		fileChecker.SetConnectionPool(cfg.MaxIdleConns, cfg.MaxIdleConnsPerHost)
+		fileChecker.SetHTTP2(cfg.EnableHTTP2)
+		if err := fileChecker.SetProxy(cfg.ProxyURL); err != nil {
+			return output.QueryTotals{}, fmt.Errorf("failed to configure proxy: %w", err)
+		}
+		fileChecker.SetUserAgents(userAgents)
+		fileChecker.SetSignatureCheck(cfg.VerifySignatures, cfg.SignatureBytes)
+		fileChecker.SetEntropyThreshold(cfg.EntropyThreshold)
+		if len(cfg.CheckExtensions) > 0 {
+			fileChecker.SetCheckExtensions(cfg.CheckExtensions)
+		}
 
 		// Set file checker in worker
-		worker.SetFileChecker(fileChecker, true, queryConfig.TargetFileName)
+		worker.SetFileChecker(fileChecker, queryConfig.Check, queryConfig.TargetFileName)
+		idleClosers = append(idleClosers, fileChecker.CloseIdleConnections)
+	}
+
+	// Optionally close idle keep-alive connections periodically during long scans
+	if cfg.IdleReapIntervalSeconds > 0 {
+		stopReaper := startIdleReaper(time.Duration(cfg.IdleReapIntervalSeconds)*time.Second, idleClosers, logger)
+		defer stopReaper()
+	}
+
+	// Optionally expose scan statistics to Prometheus while hosts are processed
+	var metricsServer *metrics.Server
+	if cfg.MetricsAddr != "" {
+		metricsServer = metrics.NewServer(cfg.MetricsAddr, func() metrics.Snapshot {
+			_, onlineHosts, totalFiles, filteredFiles, checkedFiles, binaryFilesFound, writeErrors, _ := worker.GetStats()
+			return metrics.Snapshot{
+				HostsProcessed:  worker.GetProcessedHosts(),
+				OnlineHosts:     onlineHosts,
+				FilesFound:      totalFiles,
+				FilteredFiles:   filteredFiles,
+				CheckedFiles:    checkedFiles,
+				BinariesFound:   binaryFilesFound,
+				WriteErrors:     writeErrors,
+				InFlightWorkers: worker.GetActiveWorkers(),
+			}
+		}, logger)
+		if err := metricsServer.Start(); err != nil {
+			logger.Error("WARNING: Metrics server not started: %v - continuing without metrics", err)
+		}
+	}
+
+	// Optionally log the statistics on a fixed schedule while hosts are processed
+	stopHeartbeat := func() {}
+	if cfg.HeartbeatIntervalSeconds > 0 {
+		stopHeartbeat = startHeartbeat(time.Duration(cfg.HeartbeatIntervalSeconds)*time.Second, worker, logger)
 	}
 
 	// Process hosts
-	worker.ProcessHosts(hosts)
+	if pipelined {
+		logger.Info("Pipelined crawl enabled - crawling hosts while Censys pages are fetched")
+		hostChan := make(chan api.Host, 1000)
+		// Receives the added IP hosts once the producer has finished, which also joins it
+		producerDone := make(chan int, 1)
+
+		go func() {
+			defer close(hostChan)
+			pipelineExtra := 0
+			jsonPath, err := pipelineClient.ExecuteQueryPipelined(ctx, queryConfig.Query, cfg.OutputDir, func(pageHosts []api.Host) {
+				pageHosts, extra := prepareHosts(cfg, pageHosts, logger)
+				pipelineExtra += extra
+				for _, host := range pageHosts {
+					// The worker stops reading on shutdown
+					select {
+					case hostChan <- host:
+					case <-ctx.Done():
+						return
+					}
+				}
+			})
+			if err != nil {
+				// Hosts from pages fetched so far are still crawled
+				logger.Error("Failed to execute Platform API v3 query: %v", err)
+			} else {
+				finishRawResults(cfg, jsonPath, runID, logger)
+			}
+			producerDone <- pipelineExtra
+		}()
+
+		worker.ProcessHostStream(ctx, hostChan)
+		extraIPHosts = <-producerDone
+		censysResults = pipelineClient.ResultCount()
+	} else {
+		worker.ProcessHosts(ctx, hosts)
+	}
+	stopHeartbeat()
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(); err != nil {
+			logger.Error("Failed to stop metrics server: %v", err)
+		}
+	}
 
 	// Get updated statistics
-	stats.totalHosts, stats.onlineHosts, stats.totalFiles, stats.filteredFiles, stats.checkedFiles, stats.binaryFilesFound, stats.writeErrors = worker.GetStats()
+	stats.totalHosts, stats.onlineHosts, stats.totalFiles, stats.filteredFiles, stats.checkedFiles, stats.binaryFilesFound, stats.writeErrors, stats.notListingHosts = worker.GetStats()
+
+	// Flag listings that exceeded the body limit so users know they are incomplete
+	truncatedURLs := client.GetTruncatedURLs()
+	for _, truncatedURL := range truncatedURLs {
+		writer.WriteRawOutput("Truncated directory listing: " + truncatedURL)
+	}
 
 	// Generate and write summary
 	endTime := time.Now()
-	summary := output.FormatSummary(
-		queryConfig.Query,
-		stats.totalHosts,
-		stats.onlineHosts,
-		stats.totalFiles,
-		stats.filteredFiles,
-		stats.checkedFiles,
-		stats.binaryFilesFound,
-		fileFilter.GetFilterExtensions(),
-		startTime,
-		endTime,
-		queryConfig.Check,
-		queryConfig.TargetFileName,
-		cfg.BinaryOutputFile,
-	)
+	summaryStats := output.SummaryStats{
+		RunID:              runID,
+		Query:              queryConfig.Query,
+		StartTime:          startTime,
+		EndTime:            endTime,
+		OutputDir:          writer.OutputDir(),
+		CensysResults:      censysResults,
+		TotalHosts:         stats.totalHosts,
+		ExtraIPHosts:       extraIPHosts,
+		OnlineHosts:        stats.onlineHosts,
+		NotListingHosts:    stats.notListingHosts,
+		SubpathListings:    worker.GetSubpathListings(),
+		MirrorHosts:        worker.GetMirrorHosts(),
+		TotalFiles:         stats.totalFiles,
+		CategoryCounts:     worker.GetCategoryCounts(),
+		LinksCapped:        worker.LinksCapped(),
+		FilteredFiles:      stats.filteredFiles,
+		Filters:            appliedFilters,
+		TruncatedListings:  len(truncatedURLs),
+		CheckEnabled:       queryConfig.Check,
+		TargetFileName:     queryConfig.TargetFileName,
+		CheckedFiles:       stats.checkedFiles,
+		ChecksCapped:       worker.ChecksCapped(),
+		BinaryFilesFound:   stats.binaryFilesFound,
+		SuppressedBinaries: worker.GetSuppressedBinaries(),
+		BinaryOutputFile:   writer.BinaryOutputPath(),
+	}
+	summary := output.FormatSummary(summaryStats)
 
 	logger.Info("\n%s", summary)
 	writer.WriteRawOutput("\n" + summary)
 
+	// Key statistics for post_scan_command
+	hookEnv = []string{
+		"CENSEI_RUN_ID=" + runID,
+		"CENSEI_QUERY=" + queryConfig.Query,
+		"CENSEI_OUTPUT_DIR=" + writer.OutputDir(),
+		"CENSEI_BINARY_FILE=" + writer.BinaryOutputPath(),
+		fmt.Sprintf("CENSEI_TOTAL_HOSTS=%d", stats.totalHosts),
+		fmt.Sprintf("CENSEI_ONLINE_HOSTS=%d", stats.onlineHosts),
+		fmt.Sprintf("CENSEI_FILES_FOUND=%d", stats.totalFiles),
+		fmt.Sprintf("CENSEI_FILTERED_FILES=%d", stats.filteredFiles),
+		fmt.Sprintf("CENSEI_BINARIES_FOUND=%d", stats.binaryFilesFound),
+		fmt.Sprintf("CENSEI_INTERRUPTED=%t", ctx.Err() != nil),
+	}
+
+	if cfg.DownloadBinaries && queryConfig.Check {
+		logger.Info("Downloaded binary files: %d", worker.GetDownloadedFiles())
+	}
+	if cfg.ReportMismatches && queryConfig.Check {
+		logger.Info("Extension/content-type mismatches: %d", worker.GetMismatchFiles())
+	}
+
+	if ctx.Err() != nil {
+		logger.Info("Scan was interrupted - partial results saved to %s", writer.OutputDir())
+		writer.WriteRawOutput("Scan interrupted: results are partial")
+	}
+
+	// Optionally post the summary to a Slack or Discord channel
+	if cfg.NotifyOnComplete {
+		title := "Censei scan completed"
+		if ctx.Err() != nil {
+			title = "Censei scan interrupted (partial results)"
+		}
+		if err := notify.SendCompletion(poster, cfg.NotifyWebhookURL, cfg.NotifyFormat, title, summary); err != nil {
+			logger.Error("WARNING: Failed to send completion notification: %v", err)
+		} else {
+			logger.Info("Sent completion notification")
+		}
+	}
+
+	// Structured reports share the metadata of the summary
+	reportMetadata := summaryStats.ReportMetadata()
+	if cfg.JSONOutput {
+		if err := writer.WriteJSONReport(reportMetadata); err != nil {
+			logger.Error("Failed to write JSON report: %v", err)
+		}
+	}
+	if cfg.OutputFormat == "markdown" {
+		if err := writer.WriteMarkdownReport(reportMetadata); err != nil {
+			logger.Error("Failed to write Markdown report: %v", err)
+		}
+	}
+
+	// Optionally keep the limit statistics for tuning the limit settings
+	if cfg.WriteScanReport {
+		scanReport := worker.ScanReport()
+		scanReport.RunID = runID
+		scanReport.Query = queryConfig.Query
+		scanReport.GeneratedAt = endTime
+		if err := writer.WriteScanReport(scanReport); err != nil {
+			logger.Error("Failed to write scan report: %v", err)
+		}
+	}
+
 	// Check for write errors and warn user
 	if stats.writeErrors > 0 {
 		warningMsg := fmt.Sprintf("\n⚠️  WARNING: %d file write errors occurred during execution!", stats.writeErrors)
@@ -387,4 +1105,104 @@ func runQueryConfig(cfg *config.Config, queryConfig *config.Query, logger *loggi
 	}
 
 	logger.Info("Query execution complete")
+
+	return output.QueryTotals{
+		Name:             queryConfig.Name,
+		TotalHosts:       stats.totalHosts,
+		OnlineHosts:      stats.onlineHosts,
+		TotalFiles:       stats.totalFiles,
+		FilteredFiles:    stats.filteredFiles,
+		BinaryFilesFound: stats.binaryFilesFound,
+		Interrupted:      ctx.Err() != nil,
+	}, nil
+}
+
+// defaultPostScanTimeout bounds post_scan_command when post_scan_timeout_seconds is 0
+const defaultPostScanTimeout = 5 * time.Minute
+
+// runPostScanCommand runs post_scan_command with the scan statistics as CENSEI_* environment
+// variables and logs its output; the command is killed after post_scan_timeout_seconds
+func runPostScanCommand(cfg *config.Config, env []string, logger *logging.Logger) error {
+	timeout := defaultPostScanTimeout
+	if cfg.PostScanTimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.PostScanTimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger.Info("Running post_scan_command: %s", strings.Join(cfg.PostScanCommand, " "))
+	cmd := exec.CommandContext(ctx, cfg.PostScanCommand[0], cfg.PostScanCommand[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	// Don't wait forever for children of a killed command that keep the output pipe open
+	cmd.WaitDelay = 5 * time.Second
+
+	output, err := cmd.CombinedOutput()
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if line != "" {
+			logger.Info("post_scan_command: %s", line)
+		}
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("post_scan_command completed")
+	return nil
+}
+
+// finishRawResults deletes the intermediate censys_results.json (keep_raw_results false) or,
+// with run_id_in_filenames, renames it to <runID>_censys_results.json so the next run keeps it
+func finishRawResults(cfg *config.Config, jsonPath string, runID string, logger *logging.Logger) {
+	if cfg.KeepRawResults != nil && !*cfg.KeepRawResults {
+		if err := os.Remove(jsonPath); err != nil {
+			logger.Error("WARNING: Failed to delete %s: %v", jsonPath, err)
+			return
+		}
+		logger.Debug("Deleted intermediate results file %s (keep_raw_results is false)", jsonPath)
+		return
+	}
+
+	if cfg.RunIDInFilenames {
+		keptPath := filepath.Join(filepath.Dir(jsonPath), runID+"_"+filepath.Base(jsonPath))
+		if err := os.Rename(jsonPath, keptPath); err != nil {
+			logger.Error("WARNING: Failed to rename %s: %v", jsonPath, err)
+			return
+		}
+		logger.Info("Censys results saved to %s", keptPath)
+	}
+}
+
+// redactProxyURL hides proxy credentials for logging
+func redactProxyURL(proxyURL string) string {
+	parsedURL, err := url.Parse(proxyURL)
+	if err != nil {
+		return "(invalid)"
+	}
+	return parsedURL.Redacted()
+}
+
+// shutdownContext returns a context that is cancelled on the first SIGINT or SIGTERM
+// A second signal terminates immediately; call the returned function to remove the handler
+func shutdownContext(logger *logging.Logger) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-sigChan:
+			logger.Info("Received %s - graceful shutdown in progress, aborting active requests. Results so far are preserved (press Ctrl+C again to exit immediately)", sig)
+			signal.Stop(sigChan)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigChan)
+		cancel()
+	}
 }
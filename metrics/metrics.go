@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"censei/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// shutdownTimeout bounds how long Shutdown waits for scrapes in progress
+const shutdownTimeout = 5 * time.Second
+
+// Snapshot holds the scan statistics exposed on /metrics
+type Snapshot struct {
+	HostsProcessed  int
+	OnlineHosts     int
+	FilesFound      int
+	FilteredFiles   int
+	CheckedFiles    int
+	BinariesFound   int
+	WriteErrors     int
+	InFlightWorkers int
+}
+
+// Server exposes scan statistics to Prometheus
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+	logger   *logging.Logger
+}
+
+// NewServer creates a metrics server on addr (e.g. "127.0.0.1:9090")
+// collect is called on every scrape and must be safe for concurrent use
+func NewServer(addr string, collect func() Snapshot, logger *logging.Logger) *Server {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newSnapshotCollector(collect))
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return &Server{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		logger: logger,
+	}
+}
+
+// Start listens on the configured address and serves scrapes in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+	s.listener = listener
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Metrics server stopped: %v", err)
+		}
+	}()
+
+	s.logger.Info("Serving Prometheus metrics on http://%s/metrics", listener.Addr())
+	return nil
+}
+
+// Shutdown stops the server after scrapes in progress have finished
+func (s *Server) Shutdown() error {
+	if s.listener == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// snapshotMetric maps a Snapshot field to a Prometheus metric
+type snapshotMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(Snapshot) int
+}
+
+// snapshotCollector reports a Snapshot taken once per scrape, so all values are consistent
+type snapshotCollector struct {
+	collect func() Snapshot
+	metrics []snapshotMetric
+}
+
+// newSnapshotCollector creates the collector for the censei_* metrics
+func newSnapshotCollector(collect func() Snapshot) *snapshotCollector {
+	counter := func(name, help string, value func(Snapshot) int) snapshotMetric {
+		return snapshotMetric{prometheus.NewDesc(name, help, nil, nil), prometheus.CounterValue, value}
+	}
+	gauge := func(name, help string, value func(Snapshot) int) snapshotMetric {
+		return snapshotMetric{prometheus.NewDesc(name, help, nil, nil), prometheus.GaugeValue, value}
+	}
+
+	return &snapshotCollector{
+		collect: collect,
+		metrics: []snapshotMetric{
+			counter("censei_hosts_processed_total", "Hosts taken from the queue, including skipped ones", func(s Snapshot) int { return s.HostsProcessed }),
+			counter("censei_online_hosts_total", "Hosts that responded", func(s Snapshot) int { return s.OnlineHosts }),
+			counter("censei_files_found_total", "Files found in directory listings", func(s Snapshot) int { return s.FilesFound }),
+			counter("censei_filtered_files_total", "Found files matching the filters", func(s Snapshot) int { return s.FilteredFiles }),
+			counter("censei_checked_files_total", "Files checked by content", func(s Snapshot) int { return s.CheckedFiles }),
+			counter("censei_binaries_found_total", "Files confirmed as binaries", func(s Snapshot) int { return s.BinariesFound }),
+			counter("censei_write_errors_total", "Errors writing output files", func(s Snapshot) int { return s.WriteErrors }),
+			gauge("censei_inflight_workers", "Workers currently processing a host", func(s Snapshot) int { return s.InFlightWorkers }),
+		},
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *snapshotCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range c.metrics {
+		ch <- metric.desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *snapshotCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.collect()
+	for _, metric := range c.metrics {
+		ch <- prometheus.MustNewConstMetric(metric.desc, metric.valueType, float64(metric.value(snapshot)))
+	}
+}
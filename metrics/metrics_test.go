@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"censei/logging"
+)
+
+func TestServerExposesSnapshot(t *testing.T) {
+	server := NewServer("127.0.0.1:0", func() Snapshot {
+		return Snapshot{HostsProcessed: 7, FilesFound: 42, InFlightWorkers: 3}
+	}, logging.NewLogger())
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Shutdown()
+
+	resp, err := http.Get("http://" + server.listener.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"# TYPE censei_hosts_processed_total counter",
+		"censei_hosts_processed_total 7",
+		"censei_files_found_total 42",
+		"censei_write_errors_total 0",
+		"# TYPE censei_inflight_workers gauge",
+		"censei_inflight_workers 3",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics response missing %q:\n%s", want, body)
+		}
+	}
+}
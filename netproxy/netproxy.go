@@ -0,0 +1,145 @@
+// Package netproxy opens TCP connections through the proxy set in proxy_url
+// Proxying at the dial level keeps custom TLS dialing (virtual host SNI, ALPN)
+// working on top of the tunnelled connection
+package netproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ContextDialer opens TCP connections
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// New returns a dialer that connects through proxyURL, reaching the proxy with forward
+// socks5:// is handled by golang.org/x/net/proxy, http:// and https:// open a CONNECT tunnel
+func New(proxyURL string, forward *net.Dialer) (ContextDialer, error) {
+	parsedURL, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch parsedURL.Scheme {
+	case "http", "https":
+		return &connectDialer{proxyURL: parsedURL, forward: forward}, nil
+	case "socks5", "socks5h":
+		dialer, err := proxy.FromURL(parsedURL, forward)
+		if err != nil {
+			return nil, err
+		}
+		contextDialer, ok := dialer.(ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported proxy scheme %q", parsedURL.Scheme)
+		}
+		return contextDialer, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", parsedURL.Scheme)
+	}
+}
+
+// NewHTTPTransport returns a copy of http.DefaultTransport whose connections go through proxyURL
+// An empty proxyURL keeps the default behavior
+func NewHTTPTransport(proxyURL string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL == "" {
+		return transport, nil
+	}
+
+	dialer, err := New(proxyURL, &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport, nil
+}
+
+// connectDialer tunnels connections through an HTTP proxy using CONNECT
+type connectDialer struct {
+	proxyURL *url.URL
+	forward  *net.Dialer
+}
+
+// DialContext connects to the proxy and asks it to open a tunnel to address
+func (d *connectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	proxyAddress := d.proxyURL.Host
+	if d.proxyURL.Port() == "" {
+		port := "80"
+		if d.proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(d.proxyURL.Hostname(), port)
+	}
+
+	conn, err := d.forward.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unblock the handshake when the request is cancelled
+	rawConn := conn
+	stop := context.AfterFunc(ctx, func() { rawConn.Close() })
+	defer stop()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if d.proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{
+			InsecureSkipVerify: true, // Same as the scanned hosts
+			ServerName:         d.proxyURL.Hostname(),
+		})
+		handshakeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user := d.proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// The client speaks first through the tunnel, so nothing follows the response yet
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused tunnel to %s: %s", address, resp.Status)
+	}
+
+	if !stop() {
+		return nil, ctx.Err()
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
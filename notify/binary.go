@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"sync"
+	"time"
+
+	"censei/logging"
+)
+
+// BinaryFinding is a confirmed binary file reported to the webhook
+type BinaryFinding struct {
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Host        string    `json:"host"`
+	FoundAt     time.Time `json:"found_at"`
+}
+
+// binaryBatch is the JSON body POSTed to the webhook
+type binaryBatch struct {
+	Count    int             `json:"count"`
+	Findings []BinaryFinding `json:"findings"`
+}
+
+// BinaryNotifier POSTs confirmed binaries to a webhook in batches
+// Findings are coalesced and sent every interval, or in one summary POST on Close if the
+// interval is 0. Delivery failures are logged and never interrupt the scan.
+type BinaryNotifier struct {
+	url    string
+	poster *Poster
+	logger *logging.Logger
+
+	pending []BinaryFinding
+	mu      sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewBinaryNotifier creates a notifier for webhookURL that sends a batch every interval
+// (0 = a single summary POST when the notifier is closed)
+func NewBinaryNotifier(webhookURL string, interval time.Duration, poster *Poster, logger *logging.Logger) *BinaryNotifier {
+	n := &BinaryNotifier{
+		url:    webhookURL,
+		poster: poster,
+		logger: logger,
+	}
+
+	if interval > 0 {
+		n.stop = make(chan struct{})
+		n.done = make(chan struct{})
+		go n.run(interval)
+	}
+	return n
+}
+
+// run sends the pending findings every interval until the notifier is closed
+func (n *BinaryNotifier) run(interval time.Duration) {
+	defer close(n.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n.flush()
+		case <-n.stop:
+			return
+		}
+	}
+}
+
+// Notify queues a finding for the next batch
+// Safe to call on a nil notifier (webhook disabled)
+func (n *BinaryNotifier) Notify(finding BinaryFinding) {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	n.pending = append(n.pending, finding)
+	n.mu.Unlock()
+}
+
+// Close stops the batch timer and sends the remaining findings
+func (n *BinaryNotifier) Close() {
+	if n == nil {
+		return
+	}
+
+	if n.stop != nil {
+		close(n.stop)
+		<-n.done
+	}
+	n.flush()
+}
+
+// flush sends all pending findings in one POST; nothing is sent if there are none
+func (n *BinaryNotifier) flush() {
+	n.mu.Lock()
+	findings := n.pending
+	n.pending = nil
+	n.mu.Unlock()
+
+	if len(findings) == 0 {
+		return
+	}
+
+	if err := n.poster.PostJSON(n.url, binaryBatch{Count: len(findings), Findings: findings}); err != nil {
+		n.logger.Error("WARNING: Failed to send %d binary findings to webhook: %v", len(findings), err)
+		return
+	}
+	n.logger.Debug("Sent %d binary findings to webhook", len(findings))
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"time"
+
+	"censei/logging"
+)
+
+// blockQueueSize is the number of block events waiting to be sent before new ones are dropped
+const blockQueueSize = 1000
+
+// BlockEvent is the JSON body POSTed to the blocklist webhook for every newly blocked host
+type BlockEvent struct {
+	Host      string    `json:"host"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// BlockNotifier POSTs newly blocked hosts to a webhook
+// Events are sent one at a time in the background, so a slow receiver never slows down scanning;
+// if blockQueueSize events are waiting, new ones are dropped with a warning
+type BlockNotifier struct {
+	url    string
+	poster *Poster
+	logger *logging.Logger
+
+	queue chan BlockEvent
+	done  chan struct{}
+}
+
+// NewBlockNotifier creates a notifier for webhookURL and starts its sender
+func NewBlockNotifier(webhookURL string, poster *Poster, logger *logging.Logger) *BlockNotifier {
+	n := &BlockNotifier{
+		url:    webhookURL,
+		poster: poster,
+		logger: logger,
+		queue:  make(chan BlockEvent, blockQueueSize),
+		done:   make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+// run sends queued events until the notifier is closed
+func (n *BlockNotifier) run() {
+	defer close(n.done)
+
+	for event := range n.queue {
+		if err := n.poster.PostJSON(n.url, event); err != nil {
+			n.logger.Error("WARNING: Failed to send blocked host %s to webhook: %v", event.Host, err)
+			continue
+		}
+		n.logger.Debug("Sent blocked host %s to webhook", event.Host)
+	}
+}
+
+// Notify queues an event for sending; it never blocks
+// Safe to call on a nil notifier (webhook disabled)
+func (n *BlockNotifier) Notify(event BlockEvent) {
+	if n == nil {
+		return
+	}
+
+	select {
+	case n.queue <- event:
+	default:
+		n.logger.Error("WARNING: Blocklist webhook queue is full, not sending blocked host %s", event.Host)
+	}
+}
+
+// Close sends the queued events and stops the sender
+func (n *BlockNotifier) Close() {
+	if n == nil {
+		return
+	}
+
+	close(n.queue)
+	<-n.done
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"censei/logging"
+)
+
+func TestBlockNotifierSendsQueuedEvents(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event BlockEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid block event: %v", err)
+		}
+		mu.Lock()
+		received = append(received, event.Host)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	notifier := NewBlockNotifier(server.URL, NewPoster(nil), logging.NewLogger())
+	for _, host := range []string{"a.example", "b.example", "c.example"} {
+		notifier.Notify(BlockEvent{Host: host, BlockedAt: time.Now()})
+	}
+	notifier.Close()
+
+	if len(received) != 3 || received[0] != "a.example" || received[2] != "c.example" {
+		t.Errorf("webhook received %v, want the 3 hosts in order", received)
+	}
+}
+
+func TestBlockNotifierDropsEventsWhenQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	notifier := NewBlockNotifier(server.URL, NewPoster(nil), logging.NewLogger())
+
+	// The receiver hangs, Notify must still return immediately
+	start := time.Now()
+	for i := 0; i < blockQueueSize+10; i++ {
+		notifier.Notify(BlockEvent{Host: "a.example", BlockedAt: time.Now()})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Notify blocked for %v with a hanging webhook", elapsed)
+	}
+
+	close(release)
+	notifier.Close()
+}
+
+func TestBlockNotifierNil(t *testing.T) {
+	var notifier *BlockNotifier
+	notifier.Notify(BlockEvent{Host: "a.example"})
+	notifier.Close()
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Chat webhook payload formats
+const (
+	FormatSlack   = "slack"
+	FormatDiscord = "discord"
+)
+
+// discordContentLimit is the maximum message length accepted by Discord webhooks
+const discordContentLimit = 2000
+
+// DetectFormat guesses the chat format of an incoming webhook from its URL
+// Returns "" if the URL is neither a Slack nor a Discord webhook
+func DetectFormat(webhookURL string) string {
+	parsedURL, err := url.Parse(webhookURL)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(parsedURL.Hostname())
+	switch {
+	case host == "hooks.slack.com":
+		return FormatSlack
+	case host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com"):
+		return FormatDiscord
+	}
+	return ""
+}
+
+// SendCompletion posts the scan summary to a Slack or Discord incoming webhook
+// format is FormatSlack or FormatDiscord; "" detects it from the URL
+func SendCompletion(poster *Poster, webhookURL, format, title, summary string) error {
+	if format == "" {
+		format = DetectFormat(webhookURL)
+	}
+
+	// The summary is aligned plain text, a code block keeps it readable
+	summary = strings.TrimSpace(summary)
+
+	var payload map[string]string
+	switch format {
+	case FormatSlack:
+		payload = map[string]string{"text": fmt.Sprintf("*%s*\n```\n%s\n```", title, summary)}
+	case FormatDiscord:
+		message := fmt.Sprintf("**%s**\n```\n%s\n```", title, summary)
+		if len(message) > discordContentLimit {
+			// Cut the summary and close the code block again
+			const ending = "\n…\n```"
+			message = strings.ToValidUTF8(message[:discordContentLimit-len(ending)], "") + ending
+		}
+		payload = map[string]string{"content": message}
+	default:
+		return fmt.Errorf("cannot tell if the webhook URL is a Slack or Discord webhook, set notify_format")
+	}
+
+	return poster.PostJSON(webhookURL, payload)
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookTimeout bounds a single webhook POST
+const webhookTimeout = 10 * time.Second
+
+// Poster sends JSON payloads to webhooks
+// One Poster is shared by all notifiers so every webhook uses the configured transport (proxy_url)
+type Poster struct {
+	client *http.Client
+}
+
+// NewPoster creates a poster sending requests over transport (nil = http.DefaultTransport)
+func NewPoster(transport http.RoundTripper) *Poster {
+	return &Poster{client: &http.Client{Timeout: webhookTimeout, Transport: transport}}
+}
+
+// PostJSON POSTs payload as JSON to webhookURL and checks for a 2xx response
+func (p *Poster) PostJSON(webhookURL string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	resp, err := p.client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
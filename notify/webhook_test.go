@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"censei/logging"
+	"censei/netproxy"
+)
+
+func TestBinaryNotifierPostsThroughProxy(t *testing.T) {
+	var batch binaryBatch
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("invalid batch: %v", err)
+		}
+	}))
+	defer receiver.Close()
+
+	// CONNECT proxy counting the tunnels it opens
+	var tunnels atomic.Int64
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer target.Close()
+		tunnels.Add(1)
+
+		conn, buffered, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go io.Copy(target, buffered)
+		io.Copy(conn, target)
+	}))
+	defer proxy.Close()
+
+	transport, err := netproxy.NewHTTPTransport(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.CloseIdleConnections()
+
+	notifier := NewBinaryNotifier(receiver.URL, 0, NewPoster(transport), logging.NewLogger())
+	notifier.Notify(BinaryFinding{URL: "http://a.example/tool.exe", Host: "http://a.example", FoundAt: time.Now()})
+	notifier.Close()
+
+	if tunnels.Load() == 0 {
+		t.Error("webhook was not sent through the proxy")
+	}
+	if batch.Count != 1 || len(batch.Findings) != 1 || batch.Findings[0].URL != "http://a.example/tool.exe" {
+		t.Errorf("webhook received %+v, want the one finding", batch)
+	}
+}
@@ -0,0 +1,147 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDownloadNameLength keeps generated filenames well below filesystem limits
+const maxDownloadNameLength = 200
+
+// DownloadRecord maps a saved binary back to its source URL in downloads.jsonl
+type DownloadRecord struct {
+	URL          string    `json:"url"`
+	Path         string    `json:"path"`
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	ResumedFrom  int64     `json:"resumed_from,omitempty"`
+	ContentType  string    `json:"content_type"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// EnableDownloads creates the directory for downloaded binaries and the downloads.jsonl manifest
+// An empty downloadsDir uses "downloads" inside the output directory
+func (w *Writer) EnableDownloads(downloadsDir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if downloadsDir == "" {
+		downloadsDir = filepath.Join(w.outputDir, "downloads")
+	}
+	if err := os.MkdirAll(downloadsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create downloads directory: %w", err)
+	}
+
+	manifestPath := filepath.Join(w.outputDir, w.filePrefix+"downloads.jsonl")
+	manifestFile, err := createOutputFile(manifestPath, w.appendFiles)
+	if err != nil {
+		return fmt.Errorf("failed to create download manifest: %w", err)
+	}
+
+	w.downloadsDir = downloadsDir
+	w.downloadNames = make(map[string]bool)
+	w.manifestFile = manifestFile
+	w.manifestWriter = bufio.NewWriterSize(manifestFile, 64*1024)
+	w.logger.Info("Binaries are downloaded to %s (manifest: %s)", downloadsDir, manifestPath)
+	return nil
+}
+
+// ReserveDownloadPath returns the path in the downloads directory for a file URL
+// The filename is derived from host and path (e.g. 10.0.0.1_8080_files_setup.exe), so a
+// later run maps the same URL to the same file and can resume or skip it. Only names
+// already taken by another URL in this run are numbered.
+// Returns an empty string if downloads are not enabled
+func (w *Writer) ReserveDownloadPath(fileURL string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.downloadNames == nil {
+		return ""
+	}
+
+	name := downloadFileName(fileURL)
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	candidate := name
+	for i := 2; w.downloadNames[candidate]; i++ {
+		candidate = base + "-" + strconv.Itoa(i) + ext
+	}
+
+	w.downloadNames[candidate] = true
+	return filepath.Join(w.downloadsDir, candidate)
+}
+
+// WriteDownloadRecord adds a downloaded binary to the manifest
+// Does nothing if downloads are not enabled
+func (w *Writer) WriteDownloadRecord(record DownloadRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode download record: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.manifestWriter == nil {
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(w.manifestWriter, string(line)); err != nil {
+		w.logger.Error("Failed to write to download manifest: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// downloadFileName builds a filesystem-safe name from the host and path of a URL
+// Only letters, digits, dots, dashes and underscores are kept, so the name can
+// never point outside the downloads directory
+func downloadFileName(fileURL string) string {
+	parts := []string{}
+	if parsedURL, err := url.Parse(fileURL); err == nil {
+		parts = append(parts, parsedURL.Hostname(), parsedURL.Port())
+		parts = append(parts, strings.Split(parsedURL.Path, "/")...)
+	} else {
+		parts = append(parts, fileURL)
+	}
+
+	segments := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if segment := safeNameSegment(part); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	// Shorten from the front to keep the filename and its extension
+	name := strings.Join(segments, "_")
+	if len(name) > maxDownloadNameLength {
+		name = strings.TrimLeft(name[len(name)-maxDownloadNameLength:], "._-")
+	}
+	if name == "" {
+		return "download"
+	}
+	return name
+}
+
+// safeNameSegment replaces unsafe characters of a name segment with dashes
+func safeNameSegment(segment string) string {
+	var safe strings.Builder
+	for _, r := range segment {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' || r == '_' {
+			safe.WriteRune(r)
+		} else {
+			safe.WriteRune('-')
+		}
+	}
+	return strings.Trim(safe.String(), ".-")
+}
@@ -2,6 +2,7 @@ package output
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
@@ -11,53 +12,116 @@ func FormatTimestamp(t time.Time) string {
 	return t.Format("2006-01-02 15:04:05")
 }
 
+// FormatSize formats a byte count in a short human-readable form (e.g. 142KB)
+func FormatSize(bytes int) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%dKB", bytes/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
+}
+
+// SummaryStats holds the counts and settings of one scan shown in its summary and reports
+type SummaryStats struct {
+	RunID             string
+	Query             string
+	StartTime         time.Time
+	EndTime           time.Time
+	OutputDir         string
+	CensysResults     int
+	TotalHosts        int
+	ExtraIPHosts      int
+	OnlineHosts       int
+	NotListingHosts   int
+	SubpathListings   int
+	MirrorHosts       int
+	TotalFiles        int
+	CategoryCounts    map[string]int
+	LinksCapped       bool
+	FilteredFiles     int
+	Filters           []string
+	TruncatedListings int
+
+	// File check results, only shown when CheckEnabled is set
+	CheckEnabled       bool
+	TargetFileName     string
+	CheckedFiles       int
+	ChecksCapped       bool
+	BinaryFilesFound   int
+	SuppressedBinaries int
+	BinaryOutputFile   string
+}
+
 // FormatSummary creates a summary of the scan results
-func FormatSummary(
-	query string,
-	totalHosts int,
-	onlineHosts int,
-	totalFiles int,
-	filteredFiles int,
-	checkedFiles int,
-	binaryFilesFound int,
-	filters []string,
-	startTime time.Time,
-	endTime time.Time,
-	downloadEnabled bool,
-	targetFileName string,
-	binaryOutputFile string,
-) string {
-	duration := endTime.Sub(startTime)
+func FormatSummary(stats SummaryStats) string {
+	duration := stats.EndTime.Sub(stats.StartTime)
 
 	var filterStr string
-	if len(filters) > 0 {
-		filterStr = strings.Join(filters, ", ")
+	if len(stats.Filters) > 0 {
+		filterStr = strings.Join(stats.Filters, ", ")
 	} else {
 		filterStr = "None"
 	}
 
 	summary := strings.Builder{}
 	summary.WriteString("=== Censei Scan Summary ===\n")
-	summary.WriteString(fmt.Sprintf("Query: %s\n", query))
-	summary.WriteString(fmt.Sprintf("Start time: %s\n", FormatTimestamp(startTime)))
-	summary.WriteString(fmt.Sprintf("End time: %s\n", FormatTimestamp(endTime)))
+	summary.WriteString(fmt.Sprintf("Run ID: %s\n", stats.RunID))
+	summary.WriteString(fmt.Sprintf("Query: %s\n", stats.Query))
+	summary.WriteString(fmt.Sprintf("Start time: %s\n", FormatTimestamp(stats.StartTime)))
+	summary.WriteString(fmt.Sprintf("End time: %s\n", FormatTimestamp(stats.EndTime)))
 	summary.WriteString(fmt.Sprintf("Duration: %s\n", duration.Round(time.Second)))
-	summary.WriteString(fmt.Sprintf("Total hosts found: %d\n", totalHosts))
-	summary.WriteString(fmt.Sprintf("Online hosts: %d\n", onlineHosts))
-	summary.WriteString(fmt.Sprintf("Total files found: %d\n", totalFiles))
-	summary.WriteString(fmt.Sprintf("Filtered files: %d\n", filteredFiles))
-	summary.WriteString(fmt.Sprintf("Applied filters: %s\n", filterStr))
+	summary.WriteString(fmt.Sprintf("Output directory: %s\n", stats.OutputDir))
+	summary.WriteString(fmt.Sprintf("Censys results: %d\n", stats.CensysResults))
+	summary.WriteString(fmt.Sprintf("Total hosts found: %d\n", stats.TotalHosts))
+	// Service URLs per Censys result, shows how much each result expanded
+	if stats.CensysResults > 0 {
+		summary.WriteString(fmt.Sprintf("Expansion ratio: %.2f hosts per result\n", float64(stats.TotalHosts)/float64(stats.CensysResults)))
+	}
+	if stats.ExtraIPHosts > 0 {
+		summary.WriteString(fmt.Sprintf("Extra IP-based hosts: %d\n", stats.ExtraIPHosts))
+	}
+	summary.WriteString(fmt.Sprintf("Online hosts: %d\n", stats.OnlineHosts))
+	summary.WriteString(fmt.Sprintf("Not a listing: %d\n", stats.NotListingHosts))
+	if stats.SubpathListings > 0 {
+		summary.WriteString(fmt.Sprintf("Listings found via subpath probing: %d\n", stats.SubpathListings))
+	}
+	if stats.MirrorHosts > 0 {
+		summary.WriteString(fmt.Sprintf("Mirror hosts skipped: %d\n", stats.MirrorHosts))
+	}
+	summary.WriteString(fmt.Sprintf("Total files found: %d\n", stats.TotalFiles))
+	if len(stats.CategoryCounts) > 0 {
+		summary.WriteString(fmt.Sprintf("Files by category: %s\n", formatCategoryCounts(stats.CategoryCounts)))
+	}
+	if stats.LinksCapped {
+		summary.WriteString("Global link cap reached: max_total_links_global hit, later files were not recorded\n")
+	}
+	summary.WriteString(fmt.Sprintf("Filtered files: %d\n", stats.FilteredFiles))
+	summary.WriteString(fmt.Sprintf("Applied stats.Filters: %s\n", filterStr))
+	if stats.TruncatedListings > 0 {
+		summary.WriteString(fmt.Sprintf("Truncated listings (incomplete): %d\n", stats.TruncatedListings))
+	}
 
 	// Add download information to summary
-	if downloadEnabled {
+	if stats.CheckEnabled {
 		summary.WriteString("File check enabled: Yes\n")
-		if targetFileName != "" {
-			summary.WriteString(fmt.Sprintf("Target filename: %s\n", targetFileName))
+		if stats.TargetFileName != "" {
+			summary.WriteString(fmt.Sprintf("Target filename: %s\n", stats.TargetFileName))
+		}
+		summary.WriteString(fmt.Sprintf("Files checked: %d\n", stats.CheckedFiles))
+		if stats.ChecksCapped {
+			summary.WriteString("File checks capped: max_checks reached, later files were not checked\n")
 		}
-		summary.WriteString(fmt.Sprintf("Files checked: %d\n", checkedFiles))
-		summary.WriteString(fmt.Sprintf("Binary files found: %d\n", binaryFilesFound))
-		if binaryFilesFound > 0 {
-			summary.WriteString(fmt.Sprintf("Binary files list: %s\n", binaryOutputFile))
+		summary.WriteString(fmt.Sprintf("Binary files found: %d\n", stats.BinaryFilesFound))
+		if stats.SuppressedBinaries > 0 {
+			summary.WriteString(fmt.Sprintf("Binary findings suppressed (max_binaries_per_host): %d\n", stats.SuppressedBinaries))
+		}
+		if stats.BinaryFilesFound > 0 {
+			summary.WriteString(fmt.Sprintf("Binary files list: %s\n", stats.BinaryOutputFile))
 		}
 	} else {
 		summary.WriteString("Download enabled: No\n")
@@ -67,3 +131,69 @@ func FormatSummary(
 
 	return summary.String()
 }
+
+// QueryTotals holds the key counts of one query of a multi-query run
+type QueryTotals struct {
+	Name             string
+	TotalHosts       int
+	OnlineHosts      int
+	TotalFiles       int
+	FilteredFiles    int
+	BinaryFilesFound int
+	Interrupted      bool
+	Failed           bool // The query could not be run, e.g. an invalid query or an API error
+}
+
+// FormatAggregateSummary creates the combined summary of several queries run in sequence
+func FormatAggregateSummary(runID string, totals []QueryTotals, startTime time.Time, endTime time.Time) string {
+	var sum QueryTotals
+	summary := strings.Builder{}
+	summary.WriteString("=== Censei Aggregate Summary ===\n")
+	summary.WriteString(fmt.Sprintf("Run ID: %s\n", runID))
+	summary.WriteString(fmt.Sprintf("Queries run: %d\n", len(totals)))
+	summary.WriteString(fmt.Sprintf("Duration: %s\n", endTime.Sub(startTime).Round(time.Second)))
+	for i, query := range totals {
+		line := fmt.Sprintf("  %d. %s: %d hosts, %d online, %d files, %d filtered, %d binaries",
+			i+1, query.Name, query.TotalHosts, query.OnlineHosts, query.TotalFiles, query.FilteredFiles, query.BinaryFilesFound)
+		if query.Failed {
+			line = fmt.Sprintf("  %d. %s: failed", i+1, query.Name)
+		} else if query.Interrupted {
+			line += " (interrupted)"
+		}
+		summary.WriteString(line + "\n")
+
+		sum.TotalHosts += query.TotalHosts
+		sum.OnlineHosts += query.OnlineHosts
+		sum.TotalFiles += query.TotalFiles
+		sum.FilteredFiles += query.FilteredFiles
+		sum.BinaryFilesFound += query.BinaryFilesFound
+	}
+	summary.WriteString(fmt.Sprintf("Total hosts found: %d\n", sum.TotalHosts))
+	summary.WriteString(fmt.Sprintf("Online hosts: %d\n", sum.OnlineHosts))
+	summary.WriteString(fmt.Sprintf("Total files found: %d\n", sum.TotalFiles))
+	summary.WriteString(fmt.Sprintf("Filtered files: %d\n", sum.FilteredFiles))
+	summary.WriteString(fmt.Sprintf("Binary files found: %d\n", sum.BinaryFilesFound))
+	summary.WriteString("================================\n")
+
+	return summary.String()
+}
+
+// formatCategoryCounts lists categories by file count, most frequent first (e.g. "executable 12, archive 3")
+func formatCategoryCounts(categoryCounts map[string]int) string {
+	categories := make([]string, 0, len(categoryCounts))
+	for category := range categoryCounts {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categoryCounts[categories[i]] != categoryCounts[categories[j]] {
+			return categoryCounts[categories[i]] > categoryCounts[categories[j]]
+		}
+		return categories[i] < categories[j]
+	})
+
+	parts := make([]string, 0, len(categories))
+	for _, category := range categories {
+		parts = append(parts, fmt.Sprintf("%s %d", category, categoryCounts[category]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,51 @@
+package output
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// titlePattern matches the HTML title of a page
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// HTTPXRecord is an online host in the JSON Lines format of httpx
+// Field names follow the httpx schema so results can be piped into tools like nuclei
+type HTTPXRecord struct {
+	Timestamp     string `json:"timestamp"`
+	URL           string `json:"url"`
+	Input         string `json:"input"`
+	Host          string `json:"host"`
+	Port          string `json:"port"`
+	Scheme        string `json:"scheme"`
+	Path          string `json:"path"`
+	Method        string `json:"method"`
+	StatusCode    int    `json:"status_code"`
+	Title         string `json:"title,omitempty"`
+	ContentLength int    `json:"content_length"`
+	ContentType   string `json:"content_type,omitempty"`
+	Webserver     string `json:"webserver,omitempty"`
+}
+
+// NewHTTPXRecord creates a record for a GET request with the current timestamp
+func NewHTTPXRecord(url, input, host, port, scheme string) HTTPXRecord {
+	return HTTPXRecord{
+		Timestamp: time.Now().Format(time.RFC3339),
+		URL:       url,
+		Input:     input,
+		Host:      host,
+		Port:      port,
+		Scheme:    scheme,
+		Path:      "/",
+		Method:    "GET",
+	}
+}
+
+// ExtractTitle returns the whitespace-normalized HTML title of a page
+func ExtractTitle(htmlContent string) string {
+	match := titlePattern.FindStringSubmatch(htmlContent)
+	if match == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(match[1]), " ")
+}
@@ -0,0 +1,47 @@
+package output
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Output modes for consecutive runs sharing an output directory
+const (
+	OutputModeOverwrite   = "overwrite"   // Write directly into the output directory (default)
+	OutputModeTimestamped = "timestamped" // Write into a subdirectory named after the start time
+	OutputModePrefixed    = "prefixed"    // Prefix filenames with the query name
+)
+
+// ResolveOutputLocation returns the directory and filename prefix for a run's output files
+func ResolveOutputLocation(mode, outputDir, queryName string, startTime time.Time) (string, string) {
+	switch mode {
+	case OutputModeTimestamped:
+		return filepath.Join(outputDir, startTime.Format("2006-01-02T15-04-05")), ""
+	case OutputModePrefixed:
+		return outputDir, filenameSafe(queryName) + "_"
+	default:
+		return outputDir, ""
+	}
+}
+
+// filenameSafe converts a query name to a lowercase filename part (e.g. "Russia OpenDir" -> "russia-opendir")
+func filenameSafe(name string) string {
+	var safe strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			safe.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			safe.WriteRune('-')
+			lastDash = true
+		}
+	}
+
+	result := strings.TrimSuffix(safe.String(), "-")
+	if result == "" {
+		return "query"
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WriteMarkdownReport writes report.md, a ready-to-paste Markdown version of the report
+// It contains the scan metadata, a summary table and a section per host with files
+// Hosts without files are only counted. Does nothing if the report is not enabled
+func (w *Writer) WriteMarkdownReport(metadata ReportMetadata) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.reportHosts == nil {
+		return nil
+	}
+
+	report := w.buildReport(metadata)
+
+	var md strings.Builder
+	md.WriteString("# Censei Scan Report\n\n")
+
+	md.WriteString("## Scan\n\n")
+	md.WriteString("| | |\n|---|---|\n")
+	writeMarkdownRow(&md, "Run ID", metadata.RunID)
+	writeMarkdownRow(&md, "Query", "`"+metadata.Query+"`")
+	writeMarkdownRow(&md, "Start time", FormatTimestamp(metadata.StartTime))
+	writeMarkdownRow(&md, "End time", FormatTimestamp(metadata.EndTime))
+	writeMarkdownRow(&md, "Duration", time.Duration(metadata.DurationSeconds*float64(time.Second)).Round(time.Second).String())
+	filters := "None"
+	if len(metadata.Filters) > 0 {
+		filters = strings.Join(metadata.Filters, ", ")
+	}
+	writeMarkdownRow(&md, "Filters", filters)
+	if metadata.TargetFileName != "" {
+		writeMarkdownRow(&md, "Target filename", metadata.TargetFileName)
+	}
+	md.WriteString("\n")
+
+	md.WriteString("## Summary\n\n")
+	md.WriteString("| Metric | Count |\n|---|---:|\n")
+	writeMarkdownRow(&md, "Censys results", fmt.Sprint(metadata.CensysResults))
+	writeMarkdownRow(&md, "Total hosts", fmt.Sprint(metadata.TotalHosts))
+	writeMarkdownRow(&md, "Online hosts", fmt.Sprint(metadata.OnlineHosts))
+	writeMarkdownRow(&md, "Not a listing", fmt.Sprint(metadata.NotListingHosts))
+	if metadata.MirrorHosts > 0 {
+		writeMarkdownRow(&md, "Mirror hosts skipped", fmt.Sprint(metadata.MirrorHosts))
+	}
+	writeMarkdownRow(&md, "Total files found", fmt.Sprint(metadata.TotalFiles))
+	writeMarkdownRow(&md, "Filtered files", fmt.Sprint(metadata.FilteredFiles))
+	if metadata.CheckEnabled {
+		writeMarkdownRow(&md, "Files checked", fmt.Sprint(metadata.CheckedFiles))
+		writeMarkdownRow(&md, "Binary files found", fmt.Sprint(metadata.BinaryFilesFound))
+		if metadata.SuppressedBinaries > 0 {
+			writeMarkdownRow(&md, "Binaries suppressed", fmt.Sprint(metadata.SuppressedBinaries))
+		}
+	}
+	if metadata.TruncatedListings > 0 {
+		writeMarkdownRow(&md, "Truncated listings", fmt.Sprint(metadata.TruncatedListings))
+	}
+	md.WriteString("\n")
+
+	md.WriteString("## Hosts\n\n")
+	withoutFiles := 0
+	for _, host := range report.Hosts {
+		if len(host.Files) == 0 && len(host.BinaryFindings) == 0 {
+			withoutFiles++
+			continue
+		}
+
+		md.WriteString(fmt.Sprintf("### `%s`\n\n", host.URL))
+		md.WriteString(fmt.Sprintf("%d files, %d filtered, %d binaries\n\n", len(host.Files), len(host.FilteredFiles), len(host.BinaryFindings)))
+
+		if len(host.BinaryFindings) > 0 {
+			md.WriteString("**Binary findings**\n\n")
+			for _, finding := range host.BinaryFindings {
+				md.WriteString(fmt.Sprintf("- `%s` (%s)\n", finding.URL, finding.ContentType))
+			}
+			if host.SuppressedBinaries > 0 {
+				md.WriteString(fmt.Sprintf("- %d more suppressed by `max_binaries_per_host`\n", host.SuppressedBinaries))
+			}
+			md.WriteString("\n")
+		}
+		if len(host.FilteredFiles) > 0 {
+			md.WriteString("**Filtered files**\n\n")
+			writeMarkdownList(&md, host.FilteredFiles)
+		}
+		if len(host.Files) > 0 {
+			// Long file lists are collapsed so the report stays readable
+			md.WriteString(fmt.Sprintf("<details>\n<summary>All files (%d)</summary>\n\n", len(host.Files)))
+			writeMarkdownList(&md, host.Files)
+			md.WriteString("</details>\n\n")
+		}
+	}
+	if withoutFiles > 0 {
+		md.WriteString(fmt.Sprintf("%d online hosts without files are not listed.\n", withoutFiles))
+	}
+
+	reportPath := filepath.Join(w.outputDir, w.filePrefix+"report.md")
+	if err := os.WriteFile(reportPath, []byte(md.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write Markdown report: %w", err)
+	}
+
+	w.logger.Info("Markdown report written: %s (%d hosts)", reportPath, len(report.Hosts)-withoutFiles)
+	return nil
+}
+
+// writeMarkdownRow writes a two-column table row, escaping characters that break the table
+func writeMarkdownRow(md *strings.Builder, name, value string) {
+	value = strings.NewReplacer("|", "\\|", "\n", " ", "\r", " ").Replace(value)
+	md.WriteString(fmt.Sprintf("| %s | %s |\n", name, value))
+}
+
+// writeMarkdownList writes URLs as a list of code spans, which keeps them from becoming live links
+func writeMarkdownList(md *strings.Builder, urls []string) {
+	for _, fileURL := range urls {
+		md.WriteString(fmt.Sprintf("- `%s`\n", fileURL))
+	}
+	md.WriteString("\n")
+}
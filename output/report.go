@@ -0,0 +1,199 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReportMetadata holds the scan details of the summary for the JSON report
+type ReportMetadata struct {
+	RunID              string    `json:"run_id"`
+	Query              string    `json:"query"`
+	StartTime          time.Time `json:"start_time"`
+	EndTime            time.Time `json:"end_time"`
+	DurationSeconds    float64   `json:"duration_seconds"`
+	CensysResults      int       `json:"censys_results"`
+	TotalHosts         int       `json:"total_hosts"`
+	OnlineHosts        int       `json:"online_hosts"`
+	NotListingHosts    int       `json:"not_listing_hosts"`
+	ExtraIPHosts       int       `json:"extra_ip_hosts"`
+	SubpathListings    int       `json:"subpath_listings"`
+	MirrorHosts        int       `json:"mirror_hosts"`
+	TotalFiles         int       `json:"total_files"`
+	FilteredFiles      int       `json:"filtered_files"`
+	TruncatedListings  int       `json:"truncated_listings"`
+	Filters            []string  `json:"filters"`
+	CheckEnabled       bool      `json:"check_enabled"`
+	TargetFileName     string    `json:"target_filename,omitempty"`
+	CheckedFiles       int       `json:"checked_files"`
+	ChecksCapped       bool      `json:"checks_capped"`
+	LinksCapped        bool      `json:"links_capped"`
+	BinaryFilesFound   int       `json:"binary_files_found"`
+	SuppressedBinaries int       `json:"suppressed_binaries"`
+}
+
+// ReportMetadata returns the report metadata for the scan
+func (s SummaryStats) ReportMetadata() ReportMetadata {
+	return ReportMetadata{
+		RunID:              s.RunID,
+		Query:              s.Query,
+		StartTime:          s.StartTime,
+		EndTime:            s.EndTime,
+		DurationSeconds:    s.EndTime.Sub(s.StartTime).Seconds(),
+		CensysResults:      s.CensysResults,
+		TotalHosts:         s.TotalHosts,
+		OnlineHosts:        s.OnlineHosts,
+		NotListingHosts:    s.NotListingHosts,
+		ExtraIPHosts:       s.ExtraIPHosts,
+		SubpathListings:    s.SubpathListings,
+		MirrorHosts:        s.MirrorHosts,
+		TotalFiles:         s.TotalFiles,
+		FilteredFiles:      s.FilteredFiles,
+		TruncatedListings:  s.TruncatedListings,
+		Filters:            s.Filters,
+		CheckEnabled:       s.CheckEnabled,
+		TargetFileName:     s.TargetFileName,
+		CheckedFiles:       s.CheckedFiles,
+		ChecksCapped:       s.ChecksCapped,
+		LinksCapped:        s.LinksCapped,
+		BinaryFilesFound:   s.BinaryFilesFound,
+		SuppressedBinaries: s.SuppressedBinaries,
+	}
+}
+
+// ReportBinary is a binary finding in the JSON report
+type ReportBinary struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+}
+
+// ReportHost is an online host with everything found on it
+type ReportHost struct {
+	URL            string         `json:"url"`
+	Files          []string       `json:"files"`
+	FilteredFiles  []string       `json:"filtered_files"`
+	BinaryFindings []ReportBinary `json:"binary_findings"`
+
+	// Binaries found but not recorded because of max_binaries_per_host
+	SuppressedBinaries int `json:"suppressed_binaries,omitempty"`
+}
+
+// Report is the structured document written to results.json
+type Report struct {
+	Metadata ReportMetadata `json:"metadata"`
+	Hosts    []*ReportHost  `json:"hosts"`
+}
+
+// EnableJSONReport collects online hosts and their files for results.json and report.md
+// The reports are written by WriteJSONReport and WriteMarkdownReport once the scan metadata is known
+func (w *Writer) EnableJSONReport() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reportHosts = make(map[string]*ReportHost)
+}
+
+// RecordReportHost adds an online host to the report
+// Does nothing if the report is not enabled
+func (w *Writer) RecordReportHost(hostURL string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.reportHosts == nil {
+		return
+	}
+	w.reportHost(hostURL)
+}
+
+// RecordReportFile adds a found file of a host to the report
+// Does nothing if the report is not enabled
+func (w *Writer) RecordReportFile(hostURL, fileURL string, filtered bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.reportHosts == nil {
+		return
+	}
+
+	host := w.reportHost(hostURL)
+	host.Files = append(host.Files, fileURL)
+	if filtered {
+		host.FilteredFiles = append(host.FilteredFiles, fileURL)
+	}
+}
+
+// reportHost returns the report entry of a host, creating it in discovery order
+// Caller must hold w.mu
+func (w *Writer) reportHost(hostURL string) *ReportHost {
+	if host, ok := w.reportHosts[hostURL]; ok {
+		return host
+	}
+
+	host := &ReportHost{
+		URL:            hostURL,
+		Files:          []string{},
+		FilteredFiles:  []string{},
+		BinaryFindings: []ReportBinary{},
+	}
+	w.reportHosts[hostURL] = host
+	w.reportOrder = append(w.reportOrder, hostURL)
+	return host
+}
+
+// WriteJSONReport writes results.json with the scan metadata and all recorded hosts
+// Does nothing if the report is not enabled
+func (w *Writer) WriteJSONReport(metadata ReportMetadata) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.reportHosts == nil {
+		return nil
+	}
+
+	report := w.buildReport(metadata)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON report: %w", err)
+	}
+
+	reportPath := filepath.Join(w.outputDir, w.filePrefix+"results.json")
+	if err := os.WriteFile(reportPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write JSON report: %w", err)
+	}
+
+	w.logger.Info("JSON report written: %s (%d hosts)", reportPath, len(report.Hosts))
+	return nil
+}
+
+// buildReport assembles the report from the metadata and all recorded hosts
+// Binary findings are attached to their host by scheme and host:port
+// Caller must hold w.mu
+func (w *Writer) buildReport(metadata ReportMetadata) Report {
+	report := Report{
+		Metadata: metadata,
+		Hosts:    make([]*ReportHost, 0, len(w.reportOrder)),
+	}
+	if report.Metadata.Filters == nil {
+		report.Metadata.Filters = []string{}
+	}
+
+	for _, hostURL := range w.reportOrder {
+		host := w.reportHosts[hostURL]
+		host.BinaryFindings = host.BinaryFindings[:0]
+		if parsedURL, err := url.Parse(hostURL); err == nil {
+			host.SuppressedBinaries = w.suppressedBinaries[parsedURL.Scheme+"://"+parsedURL.Host]
+			for _, finding := range w.binaryFindings[parsedURL.Scheme+"://"+parsedURL.Host] {
+				host.BinaryFindings = append(host.BinaryFindings, ReportBinary{
+					URL:         finding.URL,
+					ContentType: finding.ContentType,
+				})
+			}
+		}
+		report.Hosts = append(report.Hosts, host)
+	}
+
+	return report
+}
@@ -0,0 +1,19 @@
+package output
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// NewRunID generates a short identifier for one Censei invocation
+// Format: YYYYMMDD-HHMMSS-xxxxxx (timestamp plus random suffix for uniqueness)
+func NewRunID(t time.Time) string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		// Fall back to nanoseconds if the random source is unavailable
+		return fmt.Sprintf("%s-%06x", t.Format("20060102-150405"), t.Nanosecond()&0xFFFFFF)
+	}
+	return t.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,59 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ScanReport is the post-mortem of the crawl limits written to scan_report.json
+// It shows which hosts hit the link limits and which consumed most of the crawl budget
+type ScanReport struct {
+	RunID       string     `json:"run_id"`
+	Query       string     `json:"query"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Limits      ScanLimits `json:"limits"`
+
+	BlockedHosts []HostSkips `json:"blocked_hosts"` // Base hosts blocked after max_skips_before_block skips
+	SkipCounts   []HostSkips `json:"skip_counts"`   // All base hosts with skipped directories
+	SkippedHosts []string    `json:"skipped_hosts"` // Host URLs skipped for the rest of the scan
+	LinkTotals   []HostLinks `json:"link_totals"`   // Links found per base host, most first
+}
+
+// ScanLimits are the limit settings the scan ran with
+type ScanLimits struct {
+	MaxLinksPerDirectory int `json:"max_links_per_directory"`
+	MaxTotalLinks        int `json:"max_total_links"`
+	MaxTotalLinksGlobal  int `json:"max_total_links_global"`
+	MaxSkipsBeforeBlock  int `json:"max_skips_before_block"`
+}
+
+// HostSkips is the number of directories skipped on a base host because of link limits
+type HostSkips struct {
+	BaseHost string `json:"base_host"`
+	Skips    int    `json:"skips"`
+}
+
+// HostLinks is the number of links found on a base host
+type HostLinks struct {
+	BaseHost string `json:"base_host"`
+	Links    int    `json:"links"`
+}
+
+// WriteScanReport writes scan_report.json
+func (w *Writer) WriteScanReport(report ScanReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode scan report: %w", err)
+	}
+
+	reportPath := filepath.Join(w.outputDir, w.filePrefix+"scan_report.json")
+	if err := os.WriteFile(reportPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write scan report: %w", err)
+	}
+
+	w.logger.Info("Scan report written: %s (%d blocked hosts, %d skipped hosts)", reportPath, len(report.BlockedHosts), len(report.SkippedHosts))
+	return nil
+}
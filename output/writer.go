@@ -2,6 +2,7 @@ package output
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"censei/logging"
 )
@@ -29,36 +31,94 @@ type Writer struct {
 	binaryWriter   *bufio.Writer
 	mu           sync.Mutex
 	logger       *logging.Logger
+	outputDir    string
+	filePrefix   string
+
+	// Append to existing output files instead of truncating them, see NewWriter
+	appendFiles bool
 
 	// Collect binary findings grouped by host for sorted output
 	binaryFindings map[string][]BinaryFinding // host -> list of findings
+
+	// Binaries not recorded because of max_binaries_per_host, see RecordSuppressedBinary
+	suppressedBinaries map[string]int // host -> count
+
+	// Optional directory output, see EnableDirectoryOutput
+	directoryFile   *os.File
+	directoryWriter *bufio.Writer
+
+	// Optional httpx-compatible JSON Lines output, see EnableHTTPXOutput
+	httpxFile   *os.File
+	httpxWriter *bufio.Writer
+
+	// Binaries-only mode buffers raw/filtered lines per host until a binary is found, see EnableBinariesOnly
+	binariesOnly bool
+	hostBuffers  map[string]*hostBuffer // host -> lines held back
+	binaryHosts  map[string]bool        // hosts with at least one binary
+
+	// Optional captured response headers as JSON Lines, see EnableHeaderOutput
+	headerFile   *os.File
+	headerWriter *bufio.Writer
+
+	// Optional TLS certificate details as JSON Lines, see EnableCertificateOutput
+	certificateFile   *os.File
+	certificateWriter *bufio.Writer
+
+	// Optional found files split by category, see EnableCategoryOutput
+	categoryOutput  bool
+	categoryFiles   map[string]*os.File
+	categoryWriters map[string]*bufio.Writer
+
+	// Optional extension inventory written on Close, see EnableExtensionOutput
+	extensionCounts map[string]int
+
+	// Optional structured JSON report, see EnableJSONReport
+	reportHosts map[string]*ReportHost
+	reportOrder []string
+
+	// Optional path-only file lines in filtered and category output, see EnablePathOnlyOutput
+	pathOnly     bool
+	pathOnlyHost bool
+
+	// Optional downloads of confirmed binaries with manifest, see EnableDownloads
+	downloadsDir   string
+	downloadNames  map[string]bool // filenames reserved in this run
+	manifestFile   *os.File
+	manifestWriter *bufio.Writer
+
+	// Optional extension/content-type mismatch findings, see EnableMismatchOutput
+	mismatchFile   *os.File
+	mismatchWriter *bufio.Writer
 }
 
 // NewWriter creates a new output writer
-func NewWriter(outputDir string, logger *logging.Logger) (*Writer, error) {
+// A non-empty filePrefix is prepended to all output filenames (e.g. a run ID)
+// With appendFiles, line-based output files are appended to instead of truncated, so several
+// queries run in sequence write combined output
+func NewWriter(outputDir string, filePrefix string, appendFiles bool, logger *logging.Logger) (*Writer, error) {
 	// Ensure output directory exists
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Create raw output file
-	rawPath := filepath.Join(outputDir, "raw.txt")
-	rawFile, err := os.Create(rawPath)
+	rawPath := filepath.Join(outputDir, filePrefix+"raw.txt")
+	rawFile, err := createOutputFile(rawPath, appendFiles)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create raw output file: %w", err)
 	}
 
 	// Create filtered output file
-	filteredPath := filepath.Join(outputDir, "filtered.txt")
-	filteredFile, err := os.Create(filteredPath)
+	filteredPath := filepath.Join(outputDir, filePrefix+"filtered.txt")
+	filteredFile, err := createOutputFile(filteredPath, appendFiles)
 	if err != nil {
 		rawFile.Close()
 		return nil, fmt.Errorf("failed to create filtered output file: %w", err)
 	}
 
 	// Create binary output file
-	binaryPath := filepath.Join(outputDir, "binary_found.txt")
-	binaryFile, err := os.Create(binaryPath)
+	binaryPath := filepath.Join(outputDir, filePrefix+"binary_found.txt")
+	binaryFile, err := createOutputFile(binaryPath, appendFiles)
 	if err != nil {
 		rawFile.Close()
 		filteredFile.Close()
@@ -81,14 +141,497 @@ func NewWriter(outputDir string, logger *logging.Logger) (*Writer, error) {
 		binaryWriter:   bufio.NewWriterSize(binaryFile, bufferSize),
 		logger:         logger,
 		binaryFindings: make(map[string][]BinaryFinding),
+		outputDir:      outputDir,
+		filePrefix:     filePrefix,
+		appendFiles:    appendFiles,
 	}, nil
 }
 
+// createOutputFile creates the file at path, or opens it for appending with appendFiles
+func createOutputFile(path string, appendFiles bool) (*os.File, error) {
+	if !appendFiles {
+		return os.Create(path)
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+}
+
+// OutputDir returns the directory the output files are written to
+func (w *Writer) OutputDir() string {
+	return w.outputDir
+}
+
+// BinaryOutputPath returns the path of binary_found.txt
+func (w *Writer) BinaryOutputPath() string {
+	return filepath.Join(w.outputDir, w.filePrefix+"binary_found.txt")
+}
+
+// hostBuffer holds the output lines of a host in binaries-only mode
+type hostBuffer struct {
+	raw      []string
+	filtered []string
+}
+
+// EnableBinariesOnly reports only hosts that serve at least one binary in raw and filtered output
+// Lines are held back per host and written once a binary is found there; call FinishHost
+// when a host is done to discard the lines of hosts without binaries
+func (w *Writer) EnableBinariesOnly() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.binariesOnly = true
+	w.hostBuffers = make(map[string]*hostBuffer)
+	w.binaryHosts = make(map[string]bool)
+}
+
+// FinishHost discards the held back lines of a host without binaries
+// Does nothing if binaries-only mode is not enabled
+func (w *Writer) FinishHost(hostURL string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.binariesOnly {
+		return
+	}
+	if key := urlHostKey(hostURL); key != "" {
+		delete(w.hostBuffers, key)
+	}
+}
+
+// holdBack buffers a line of a host without binaries in binaries-only mode
+// Returns false if the line should be written directly
+// Caller must hold w.mu
+func (w *Writer) holdBack(line string, filtered bool) bool {
+	if !w.binariesOnly {
+		return false
+	}
+
+	// Lines without a URL (summary, warnings) are always written
+	key := lineHostKey(line)
+	if key == "" || w.binaryHosts[key] {
+		return false
+	}
+
+	buffer, ok := w.hostBuffers[key]
+	if !ok {
+		buffer = &hostBuffer{}
+		w.hostBuffers[key] = buffer
+	}
+	if filtered {
+		buffer.filtered = append(buffer.filtered, line)
+	} else {
+		buffer.raw = append(buffer.raw, line)
+	}
+	return true
+}
+
+// releaseHost writes the held back lines of a host once it served a binary
+// Caller must hold w.mu
+func (w *Writer) releaseHost(key string) {
+	if !w.binariesOnly || w.binaryHosts[key] {
+		return
+	}
+	w.binaryHosts[key] = true
+
+	buffer, ok := w.hostBuffers[key]
+	if !ok {
+		return
+	}
+	delete(w.hostBuffers, key)
+
+	for _, line := range buffer.raw {
+		if _, err := fmt.Fprintln(w.rawWriter, line); err != nil {
+			w.logger.Error("Failed to write to raw output: %v", err)
+		}
+	}
+	for _, line := range buffer.filtered {
+		if _, err := fmt.Fprintln(w.filteredWriter, w.fileLine(line)); err != nil {
+			w.logger.Error("Failed to write to filtered output: %v", err)
+		}
+	}
+}
+
+// EnablePathOnlyOutput writes found files as their path (e.g. /backup/db.sql) instead of the full URL
+// With includeHost the scheme://host follows the path, separated by a tab
+func (w *Writer) EnablePathOnlyOutput(includeHost bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pathOnly = true
+	w.pathOnlyHost = includeHost
+}
+
+// fileLine returns the output line of a found file URL
+// Caller must hold w.mu
+func (w *Writer) fileLine(fileURL string) string {
+	if !w.pathOnly {
+		return fileURL
+	}
+
+	parsedURL, err := url.Parse(fileURL)
+	if err != nil || parsedURL.Host == "" {
+		return fileURL
+	}
+
+	filePath := parsedURL.Path
+	if filePath == "" {
+		filePath = "/"
+	}
+	if parsedURL.RawQuery != "" {
+		filePath += "?" + parsedURL.RawQuery
+	}
+	// Keep the entry name of files inside archives
+	if parsedURL.Fragment != "" {
+		filePath += "#" + parsedURL.Fragment
+	}
+
+	if w.pathOnlyHost {
+		return filePath + "\t" + parsedURL.Scheme + "://" + parsedURL.Host
+	}
+	return filePath
+}
+
+// lineHostKey returns the scheme://host of the first URL in an output line
+func lineHostKey(line string) string {
+	for _, field := range strings.Fields(line) {
+		if strings.Contains(field, "://") {
+			return urlHostKey(field)
+		}
+	}
+	return ""
+}
+
+// urlHostKey returns the scheme://host of a URL, as used to group binary findings
+func urlHostKey(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Host == "" {
+		return ""
+	}
+	return parsedURL.Scheme + "://" + parsedURL.Host
+}
+
+// EnableDirectoryOutput creates directories.txt for discovered directory URLs
+func (w *Writer) EnableDirectoryOutput() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	directoryPath := filepath.Join(w.outputDir, w.filePrefix+"directories.txt")
+	directoryFile, err := createOutputFile(directoryPath, w.appendFiles)
+	if err != nil {
+		return fmt.Errorf("failed to create directory output file: %w", err)
+	}
+
+	w.directoryFile = directoryFile
+	w.directoryWriter = bufio.NewWriterSize(directoryFile, 64*1024)
+	w.logger.Info("Directory output file created: %s", directoryPath)
+	return nil
+}
+
+// WriteDirectoryOutput writes a directory URL to the directory output file
+// Does nothing if directory output is not enabled
+func (w *Writer) WriteDirectoryOutput(line string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.directoryWriter == nil {
+		return nil
+	}
+
+	_, err := fmt.Fprintln(w.directoryWriter, line)
+	if err != nil {
+		w.logger.Error("Failed to write to directory output: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// EnableMismatchOutput creates mismatches.txt for files whose extension promises a binary
+// but whose Content-Type does not (e.g. a .exe served as text/html)
+func (w *Writer) EnableMismatchOutput() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	mismatchPath := filepath.Join(w.outputDir, w.filePrefix+"mismatches.txt")
+	mismatchFile, err := createOutputFile(mismatchPath, w.appendFiles)
+	if err != nil {
+		return fmt.Errorf("failed to create mismatch output file: %w", err)
+	}
+
+	w.mismatchFile = mismatchFile
+	w.mismatchWriter = bufio.NewWriterSize(mismatchFile, 64*1024)
+	w.logger.Info("Mismatch output file created: %s", mismatchPath)
+	return nil
+}
+
+// WriteMismatchOutput writes an extension/content-type mismatch finding
+// Does nothing if mismatch output is not enabled
+func (w *Writer) WriteMismatchOutput(line string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.mismatchWriter == nil {
+		return nil
+	}
+
+	_, err := fmt.Fprintln(w.mismatchWriter, line)
+	if err != nil {
+		w.logger.Error("Failed to write to mismatch output: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// EnableHTTPXOutput creates httpx.jsonl for online hosts in the httpx JSON Lines format
+func (w *Writer) EnableHTTPXOutput() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	httpxPath := filepath.Join(w.outputDir, w.filePrefix+"httpx.jsonl")
+	httpxFile, err := createOutputFile(httpxPath, w.appendFiles)
+	if err != nil {
+		return fmt.Errorf("failed to create httpx output file: %w", err)
+	}
+
+	w.httpxFile = httpxFile
+	w.httpxWriter = bufio.NewWriterSize(httpxFile, 64*1024)
+	w.logger.Info("httpx output file created: %s", httpxPath)
+	return nil
+}
+
+// HTTPXOutputEnabled reports whether httpx records are written
+func (w *Writer) HTTPXOutputEnabled() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.httpxWriter != nil
+}
+
+// WriteHTTPXRecord writes an online host as one JSON line
+// Does nothing if httpx output is not enabled
+func (w *Writer) WriteHTTPXRecord(record HTTPXRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode httpx record: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.httpxWriter == nil {
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(w.httpxWriter, string(line)); err != nil {
+		w.logger.Error("Failed to write to httpx output: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// HeaderRecord holds the captured response headers of an online host
+type HeaderRecord struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+// EnableHeaderOutput creates headers.jsonl for captured response headers
+func (w *Writer) EnableHeaderOutput() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	headerPath := filepath.Join(w.outputDir, w.filePrefix+"headers.jsonl")
+	headerFile, err := createOutputFile(headerPath, w.appendFiles)
+	if err != nil {
+		return fmt.Errorf("failed to create header output file: %w", err)
+	}
+
+	w.headerFile = headerFile
+	w.headerWriter = bufio.NewWriterSize(headerFile, 64*1024)
+	w.logger.Info("Header output file created: %s", headerPath)
+	return nil
+}
+
+// WriteHeaderRecord writes the captured headers of a host as one JSON line
+// Does nothing if header output is not enabled
+func (w *Writer) WriteHeaderRecord(record HeaderRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode header record: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.headerWriter == nil {
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(w.headerWriter, string(line)); err != nil {
+		w.logger.Error("Failed to write to header output: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// CertificateRecord holds the TLS certificate details of an HTTPS host
+type CertificateRecord struct {
+	URL         string    `json:"url"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	SANs        []string  `json:"sans"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	Expired     bool      `json:"expired"`
+	SelfSigned  bool      `json:"self_signed"`
+	Fingerprint string    `json:"fingerprint_sha256"`
+}
+
+// EnableCertificateOutput creates certificates.jsonl for TLS certificate details
+func (w *Writer) EnableCertificateOutput() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	certificatePath := filepath.Join(w.outputDir, w.filePrefix+"certificates.jsonl")
+	certificateFile, err := createOutputFile(certificatePath, w.appendFiles)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate output file: %w", err)
+	}
+
+	w.certificateFile = certificateFile
+	w.certificateWriter = bufio.NewWriterSize(certificateFile, 64*1024)
+	w.logger.Info("Certificate output file created: %s", certificatePath)
+	return nil
+}
+
+// WriteCertificateRecord writes the certificate details of a host as one JSON line
+// Does nothing if certificate output is not enabled
+func (w *Writer) WriteCertificateRecord(record CertificateRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode certificate record: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.certificateWriter == nil {
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(w.certificateWriter, string(line)); err != nil {
+		w.logger.Error("Failed to write to certificate output: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// EnableCategoryOutput writes found files to one files_<category>.txt per category
+// Files are created on the first file of a category
+func (w *Writer) EnableCategoryOutput() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.categoryOutput = true
+	w.categoryFiles = make(map[string]*os.File)
+	w.categoryWriters = make(map[string]*bufio.Writer)
+}
+
+// WriteCategoryOutput writes a found file to the output file of its category
+// Does nothing if category output is not enabled
+func (w *Writer) WriteCategoryOutput(category string, line string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.categoryOutput {
+		return nil
+	}
+
+	categoryWriter, ok := w.categoryWriters[category]
+	if !ok {
+		categoryPath := filepath.Join(w.outputDir, w.filePrefix+"files_"+filenameSafe(category)+".txt")
+		categoryFile, err := createOutputFile(categoryPath, w.appendFiles)
+		if err != nil {
+			return fmt.Errorf("failed to create category output file: %w", err)
+		}
+		w.categoryFiles[category] = categoryFile
+		categoryWriter = bufio.NewWriterSize(categoryFile, 64*1024)
+		w.categoryWriters[category] = categoryWriter
+		w.logger.Debug("Category output file created: %s", categoryPath)
+	}
+
+	if _, err := fmt.Fprintln(categoryWriter, w.fileLine(line)); err != nil {
+		w.logger.Error("Failed to write to %s category output: %v", category, err)
+		return err
+	}
+
+	return nil
+}
+
+// EnableExtensionOutput collects file extensions for extensions.txt, written on Close
+func (w *Writer) EnableExtensionOutput() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.extensionCounts = make(map[string]int)
+}
+
+// RecordExtension counts one found file with the given extension
+// Does nothing if the extension inventory is not enabled
+func (w *Writer) RecordExtension(extension string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.extensionCounts == nil {
+		return
+	}
+	w.extensionCounts[extension]++
+}
+
+// writeExtensionInventory writes all recorded extensions sorted by count (descending)
+func (w *Writer) writeExtensionInventory() error {
+	extensionPath := filepath.Join(w.outputDir, w.filePrefix+"extensions.txt")
+	extensionFile, err := os.Create(extensionPath)
+	if err != nil {
+		return fmt.Errorf("failed to create extension output file: %w", err)
+	}
+	defer extensionFile.Close()
+
+	extensions := make([]string, 0, len(w.extensionCounts))
+	for extension := range w.extensionCounts {
+		extensions = append(extensions, extension)
+	}
+	sort.Slice(extensions, func(i, j int) bool {
+		if w.extensionCounts[extensions[i]] != w.extensionCounts[extensions[j]] {
+			return w.extensionCounts[extensions[i]] > w.extensionCounts[extensions[j]]
+		}
+		return extensions[i] < extensions[j]
+	})
+
+	extensionWriter := bufio.NewWriter(extensionFile)
+	for _, extension := range extensions {
+		if _, err := fmt.Fprintf(extensionWriter, "%d\t%s\n", w.extensionCounts[extension], extension); err != nil {
+			return fmt.Errorf("failed to write extension inventory: %w", err)
+		}
+	}
+	if err := extensionWriter.Flush(); err != nil {
+		return fmt.Errorf("failed to write extension inventory: %w", err)
+	}
+
+	w.logger.Info("Extension inventory written: %s (%d extensions)", extensionPath, len(extensions))
+	return nil
+}
+
 // WriteRawOutput writes a line to the raw output file using buffered I/O
 func (w *Writer) WriteRawOutput(line string) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	if w.holdBack(line, false) {
+		return nil
+	}
+
 	_, err := fmt.Fprintln(w.rawWriter, line)
 	if err != nil {
 		w.logger.Error("Failed to write to raw output: %v", err)
@@ -103,7 +646,11 @@ func (w *Writer) WriteFilteredOutput(line string) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	_, err := fmt.Fprintln(w.filteredWriter, line)
+	if w.holdBack(line, true) {
+		return nil
+	}
+
+	_, err := fmt.Fprintln(w.filteredWriter, w.fileLine(line))
 	if err != nil {
 		w.logger.Error("Failed to write to filtered output: %v", err)
 		return err
@@ -138,6 +685,9 @@ func (w *Writer) WriteBinaryOutput(line string) error {
 
 	host := parsedURL.Scheme + "://" + parsedURL.Host
 
+	// Report the lines held back for this host now that it served a binary
+	w.releaseHost(host)
+
 	// Check if this URL already exists for this host to avoid duplicates
 	for _, existing := range w.binaryFindings[host] {
 		if existing.URL == fileURL {
@@ -155,6 +705,22 @@ func (w *Writer) WriteBinaryOutput(line string) error {
 	return nil
 }
 
+// RecordSuppressedBinary counts a binary that was not recorded because its host reached
+// max_binaries_per_host. The count is noted in the host's section of binary_found.txt
+func (w *Writer) RecordSuppressedBinary(fileURL string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	parsedURL, err := url.Parse(fileURL)
+	if err != nil {
+		return
+	}
+	if w.suppressedBinaries == nil {
+		w.suppressedBinaries = make(map[string]int)
+	}
+	w.suppressedBinaries[parsedURL.Scheme+"://"+parsedURL.Host]++
+}
+
 // writeSortedBinaryFindings writes all binary findings grouped by host in sorted order
 func (w *Writer) writeSortedBinaryFindings() error {
 	if len(w.binaryFindings) == 0 {
@@ -177,6 +743,9 @@ func (w *Writer) writeSortedBinaryFindings() error {
 
 		// Write host separator
 		separator := fmt.Sprintf("\n=== %s (%d files) ===\n", host, len(findings))
+		if suppressed := w.suppressedBinaries[host]; suppressed > 0 {
+			separator = fmt.Sprintf("\n=== %s (%d files, %d more suppressed by max_binaries_per_host) ===\n", host, len(findings), suppressed)
+		}
 		if _, err := w.binaryWriter.WriteString(separator); err != nil {
 			return fmt.Errorf("failed to write host separator: %w", err)
 		}
@@ -239,6 +808,151 @@ func (w *Writer) Close() error {
 		w.binaryWriter = nil
 	}
 
+	// Flush and close optional directory output
+	var directoryErr error
+	if w.directoryWriter != nil {
+		directoryErr = w.directoryWriter.Flush()
+		if directoryErr != nil {
+			w.logger.Error("Failed to flush directory output buffer: %v", directoryErr)
+		}
+		w.directoryWriter = nil
+	}
+	if w.directoryFile != nil {
+		if err := w.directoryFile.Close(); err != nil {
+			w.logger.Error("Failed to close directory output file: %v", err)
+			if directoryErr == nil {
+				directoryErr = err
+			}
+		}
+		w.directoryFile = nil
+	}
+
+	// Write the optional extension inventory
+	var extensionErr error
+	if w.extensionCounts != nil {
+		extensionErr = w.writeExtensionInventory()
+		if extensionErr != nil {
+			w.logger.Error("Failed to write extension inventory: %v", extensionErr)
+		}
+		w.extensionCounts = nil
+	}
+
+	// Flush and close optional httpx output
+	var httpxErr error
+	if w.httpxWriter != nil {
+		httpxErr = w.httpxWriter.Flush()
+		if httpxErr != nil {
+			w.logger.Error("Failed to flush httpx output buffer: %v", httpxErr)
+		}
+		w.httpxWriter = nil
+	}
+	if w.httpxFile != nil {
+		if err := w.httpxFile.Close(); err != nil {
+			w.logger.Error("Failed to close httpx output file: %v", err)
+			if httpxErr == nil {
+				httpxErr = err
+			}
+		}
+		w.httpxFile = nil
+	}
+
+	// Flush and close optional category outputs
+	var categoryErr error
+	for category, categoryWriter := range w.categoryWriters {
+		if err := categoryWriter.Flush(); err != nil {
+			w.logger.Error("Failed to flush %s category output buffer: %v", category, err)
+			if categoryErr == nil {
+				categoryErr = err
+			}
+		}
+		if err := w.categoryFiles[category].Close(); err != nil {
+			w.logger.Error("Failed to close %s category output file: %v", category, err)
+			if categoryErr == nil {
+				categoryErr = err
+			}
+		}
+	}
+	w.categoryOutput = false
+	w.categoryWriters = nil
+	w.categoryFiles = nil
+
+	// Flush and close optional header output
+	var headerErr error
+	if w.headerWriter != nil {
+		headerErr = w.headerWriter.Flush()
+		if headerErr != nil {
+			w.logger.Error("Failed to flush header output buffer: %v", headerErr)
+		}
+		w.headerWriter = nil
+	}
+	if w.headerFile != nil {
+		if err := w.headerFile.Close(); err != nil {
+			w.logger.Error("Failed to close header output file: %v", err)
+			if headerErr == nil {
+				headerErr = err
+			}
+		}
+		w.headerFile = nil
+	}
+
+	// Flush and close optional certificate output
+	var certificateErr error
+	if w.certificateWriter != nil {
+		certificateErr = w.certificateWriter.Flush()
+		if certificateErr != nil {
+			w.logger.Error("Failed to flush certificate output buffer: %v", certificateErr)
+		}
+		w.certificateWriter = nil
+	}
+	if w.certificateFile != nil {
+		if err := w.certificateFile.Close(); err != nil {
+			w.logger.Error("Failed to close certificate output file: %v", err)
+			if certificateErr == nil {
+				certificateErr = err
+			}
+		}
+		w.certificateFile = nil
+	}
+
+	// Flush and close the optional download manifest
+	var manifestErr error
+	if w.manifestWriter != nil {
+		manifestErr = w.manifestWriter.Flush()
+		if manifestErr != nil {
+			w.logger.Error("Failed to flush download manifest buffer: %v", manifestErr)
+		}
+		w.manifestWriter = nil
+	}
+	if w.manifestFile != nil {
+		if err := w.manifestFile.Close(); err != nil {
+			w.logger.Error("Failed to close download manifest: %v", err)
+			if manifestErr == nil {
+				manifestErr = err
+			}
+		}
+		w.manifestFile = nil
+	}
+	w.downloadNames = nil
+
+	// Flush and close optional mismatch output
+	var mismatchErr error
+	if w.mismatchWriter != nil {
+		mismatchErr = w.mismatchWriter.Flush()
+		if mismatchErr != nil {
+			w.logger.Error("Failed to flush mismatch output buffer: %v", mismatchErr)
+		}
+		w.mismatchWriter = nil
+	}
+	if w.mismatchFile != nil {
+		if err := w.mismatchFile.Close(); err != nil {
+			w.logger.Error("Failed to close mismatch output file: %v", err)
+			if mismatchErr == nil {
+				mismatchErr = err
+			}
+		}
+		w.mismatchFile = nil
+	}
+
 	// Close files after flushing
 	if w.rawFile != nil {
 		rawErr = w.rawFile.Close()
@@ -283,6 +997,30 @@ func (w *Writer) Close() error {
 	if binaryErr != nil {
 		return binaryErr
 	}
+	if directoryErr != nil {
+		return directoryErr
+	}
+	if httpxErr != nil {
+		return httpxErr
+	}
+	if headerErr != nil {
+		return headerErr
+	}
+	if certificateErr != nil {
+		return certificateErr
+	}
+	if categoryErr != nil {
+		return categoryErr
+	}
+	if extensionErr != nil {
+		return extensionErr
+	}
+	if manifestErr != nil {
+		return manifestErr
+	}
+	if mismatchErr != nil {
+		return mismatchErr
+	}
 
 	w.logger.Info("Output files closed successfully")
 	return nil
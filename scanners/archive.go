@@ -0,0 +1,158 @@
+package scanners
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"censei/api"
+)
+
+// RangeClient interface for ranged HTTP requests used to read archive indexes
+type RangeClient interface {
+	FetchRange(ctx context.Context, host api.Host, byteRange string, maxBytes int64) ([]byte, error)
+}
+
+// ArchiveEntry is a file inside a remote archive
+type ArchiveEntry struct {
+	Name string
+	Size uint64
+}
+
+const (
+	// maxEOCDSearch covers the end of central directory record plus the longest possible comment
+	maxEOCDSearch = 22 + 65535
+	// maxCentralDirectory limits how much of a central directory is fetched (16 MB)
+	maxCentralDirectory = 16 << 20
+	// centralHeaderSize is the fixed part of a central directory header, the minimum per entry
+	centralHeaderSize = 46
+)
+
+var (
+	eocdSignature          = []byte("PK\x05\x06")
+	zip64LocatorSignature  = []byte("PK\x06\x07")
+	zip64EOCDSignature     = []byte("PK\x06\x06")
+	centralHeaderSignature = []byte("PK\x01\x02")
+)
+
+// IsListableArchive checks if a URL points to an archive whose contents can be listed remotely
+// Only ZIP-based formats keep an index at the end; TAR must be downloaded completely
+func IsListableArchive(fileURL string) bool {
+	parsedURL, err := url.Parse(fileURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(path.Ext(parsedURL.Path), ".zip")
+}
+
+// ListZipContents enumerates the files in a remote ZIP archive without downloading it
+// Reads the end of central directory record and the central directory via range requests
+func (ds *DirectoryScanner) ListZipContents(ctx context.Context, fileURL string, client RangeClient) ([]ArchiveEntry, error) {
+	host := api.Host{URL: fileURL}
+
+	// Locate the end of central directory record
+	tail, err := client.FetchRange(ctx, host, fmt.Sprintf("bytes=-%d", maxEOCDSearch), maxEOCDSearch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch archive tail: %w", err)
+	}
+
+	eocdPos := bytes.LastIndex(tail, eocdSignature)
+	if eocdPos < 0 || len(tail)-eocdPos < 22 {
+		return nil, fmt.Errorf("end of central directory not found")
+	}
+	eocd := tail[eocdPos:]
+
+	entryCount := uint64(binary.LittleEndian.Uint16(eocd[10:12]))
+	directorySize := uint64(binary.LittleEndian.Uint32(eocd[12:16]))
+	directoryOffset := uint64(binary.LittleEndian.Uint32(eocd[16:20]))
+
+	// ZIP64 archives store the real values in a separate record referenced by a locator
+	if directoryOffset == 0xFFFFFFFF || directorySize == 0xFFFFFFFF || entryCount == 0xFFFF {
+		entryCount, directorySize, directoryOffset, err = ds.readZip64EOCD(ctx, host, tail, eocdPos, client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if directorySize > maxCentralDirectory {
+		return nil, fmt.Errorf("central directory too large (%d bytes)", directorySize)
+	}
+	if directorySize == 0 {
+		return nil, nil
+	}
+	// The count comes from the server, a directory cannot hold more entries than headers fit
+	if entryCount > directorySize/centralHeaderSize {
+		return nil, fmt.Errorf("central directory of %d bytes cannot hold %d entries", directorySize, entryCount)
+	}
+
+	directory, err := client.FetchRange(ctx, host, fmt.Sprintf("bytes=%d-%d", directoryOffset, directoryOffset+directorySize-1), int64(directorySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch central directory: %w", err)
+	}
+
+	entries := parseCentralDirectory(directory, entryCount)
+	ds.logger.Debug("Listed %d entries in archive %s", len(entries), fileURL)
+	return entries, nil
+}
+
+// readZip64EOCD reads entry count, size and offset of the central directory from the ZIP64 record
+func (ds *DirectoryScanner) readZip64EOCD(ctx context.Context, host api.Host, tail []byte, eocdPos int, client RangeClient) (uint64, uint64, uint64, error) {
+	locatorPos := eocdPos - 20
+	if locatorPos < 0 || !bytes.Equal(tail[locatorPos:locatorPos+4], zip64LocatorSignature) {
+		return 0, 0, 0, fmt.Errorf("zip64 locator not found")
+	}
+	recordOffset := binary.LittleEndian.Uint64(tail[locatorPos+8 : locatorPos+16])
+
+	record, err := client.FetchRange(ctx, host, fmt.Sprintf("bytes=%d-%d", recordOffset, recordOffset+55), 56)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to fetch zip64 record: %w", err)
+	}
+	if len(record) < 56 || !bytes.Equal(record[:4], zip64EOCDSignature) {
+		return 0, 0, 0, fmt.Errorf("invalid zip64 end of central directory record")
+	}
+
+	entryCount := binary.LittleEndian.Uint64(record[32:40])
+	directorySize := binary.LittleEndian.Uint64(record[40:48])
+	directoryOffset := binary.LittleEndian.Uint64(record[48:56])
+	return entryCount, directorySize, directoryOffset, nil
+}
+
+// parseCentralDirectory extracts file names and sizes from central directory headers
+// Directory entries (names ending in "/") are skipped
+func parseCentralDirectory(directory []byte, entryCount uint64) []ArchiveEntry {
+	// entryCount is untrusted, the preallocation is bounded by what the directory can hold
+	capacity := uint64(len(directory) / centralHeaderSize)
+	if entryCount < capacity {
+		capacity = entryCount
+	}
+	entries := make([]ArchiveEntry, 0, capacity)
+
+	for pos := 0; pos+centralHeaderSize <= len(directory); {
+		if !bytes.Equal(directory[pos:pos+4], centralHeaderSignature) {
+			break
+		}
+
+		size := uint64(binary.LittleEndian.Uint32(directory[pos+24 : pos+28]))
+		nameLength := int(binary.LittleEndian.Uint16(directory[pos+28 : pos+30]))
+		extraLength := int(binary.LittleEndian.Uint16(directory[pos+30 : pos+32]))
+		commentLength := int(binary.LittleEndian.Uint16(directory[pos+32 : pos+34]))
+
+		nameEnd := pos + centralHeaderSize + nameLength
+		if nameEnd > len(directory) {
+			break
+		}
+		name := string(directory[pos+centralHeaderSize : nameEnd])
+
+		if !strings.HasSuffix(name, "/") {
+			entries = append(entries, ArchiveEntry{Name: name, Size: size})
+		}
+
+		pos = nameEnd + extraLength + commentLength
+	}
+
+	return entries
+}
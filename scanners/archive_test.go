@@ -0,0 +1,61 @@
+package scanners
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// centralHeader builds a central directory header for name with the given uncompressed size
+func centralHeader(name string, size uint32) []byte {
+	header := make([]byte, centralHeaderSize, centralHeaderSize+len(name))
+	copy(header, centralHeaderSignature)
+	binary.LittleEndian.PutUint32(header[24:28], size)
+	binary.LittleEndian.PutUint16(header[28:30], uint16(len(name)))
+	return append(header, name...)
+}
+
+func TestParseCentralDirectory(t *testing.T) {
+	directory := append(centralHeader("docs/", 0), centralHeader("docs/setup.exe", 1024)...)
+	directory = append(directory, centralHeader("readme.txt", 12)...)
+
+	tests := []struct {
+		name       string
+		directory  []byte
+		entryCount uint64
+		want       []ArchiveEntry
+	}{
+		{
+			name:       "files without directories",
+			directory:  directory,
+			entryCount: 3,
+			want:       []ArchiveEntry{{Name: "docs/setup.exe", Size: 1024}, {Name: "readme.txt", Size: 12}},
+		},
+		{
+			// A hostile ZIP64 record must not size the allocation
+			name:       "huge entry count",
+			directory:  directory,
+			entryCount: 1 << 63,
+			want:       []ArchiveEntry{{Name: "docs/setup.exe", Size: 1024}, {Name: "readme.txt", Size: 12}},
+		},
+		{
+			name:       "truncated name",
+			directory:  centralHeader("setup.exe", 1)[:centralHeaderSize+3],
+			entryCount: 1,
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseCentralDirectory(tt.directory, tt.entryCount)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d entries %v, want %v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("entry %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package scanners
 
 import (
+	"context"
+	"fmt"
 	"net/url"
 	"strings"
 	"sync/atomic"
@@ -14,49 +16,223 @@ import (
 
 // HTTPClient interface for HTTP requests in scanner
 type HTTPClient interface {
-	CheckHostAndFetch(host api.Host) (bool, string, error)
+	CheckHostAndFetch(ctx context.Context, host api.Host) (bool, string, string, error)
 }
 
 // DirectoryScanner handles scanning of open directory listings
 type DirectoryScanner struct {
-	logger          *logging.Logger
-	totalLinksCount int64
+	logger            *logging.Logger
+	totalLinksCount   int64
+	stripQueryParams  map[string]bool // Cache-busting query parameters removed from links
+	stripAllQueryArgs bool
+	allowedSchemes    map[string]bool // Link schemes kept after resolution, others are dropped
+
+	// Listing detection, see SetDirectoryIndicators and SetListingLinkThreshold
+	directoryIndicators []string
+	headingIndicators   []string
+	linkThreshold       int
+
+	// Anchors processed per page before the rest is ignored, see SetMaxAnchorsPerPage
+	maxAnchors int
+
+	// Slots for directory fetches during recursion across all hosts (nil = unlimited),
+	// see SetMaxConcurrentDirFetches
+	dirFetchSlots chan struct{}
 }
 
+// defaultLinkSchemes are the link schemes kept when none are configured
+var defaultLinkSchemes = []string{"http", "https"}
+
+// defaultDirectoryIndicators are directory listing patterns that rarely appear outside listing markup
+var defaultDirectoryIndicators = []string{
+	"parent directory",
+	"apache/", // Apache directory listings
+	"nginx/",  // Nginx directory listings
+
+	// Localized variants served by language-negotiating servers
+	"répertoire parent",          // French
+	"übergeordnetes verzeichnis", // German
+}
+
+// defaultHeadingIndicators are phrases that also occur in prose ("An index of resources"),
+// so they only count when a title or heading starts with them, as in "Index of /files"
+var defaultHeadingIndicators = []string{
+	"index of",
+	"directory listing",
+
+	// Localized variants served by language-negotiating servers
+	"índice de",  // Spanish/Portuguese
+	"inhalt von", // German
+	"indice di",  // Italian
+}
+
+// defaultListingLinkThreshold is the number of file links above which a page counts as a listing
+const defaultListingLinkThreshold = 5
+
+// defaultMaxAnchorsPerPage bounds the <a> tags processed per page; far above real listings,
+// it stops hostile pages with millions of links from tying up a worker
+const defaultMaxAnchorsPerPage = 100000
+
 // NewDirectoryScanner creates a new directory scanner instance
 func NewDirectoryScanner(logger *logging.Logger) *DirectoryScanner {
-	return &DirectoryScanner{
-		logger:          logger,
-		totalLinksCount: 0,
+	ds := &DirectoryScanner{
+		logger:              logger,
+		totalLinksCount:     0,
+		directoryIndicators: defaultDirectoryIndicators,
+		headingIndicators:   defaultHeadingIndicators,
+		linkThreshold:       defaultListingLinkThreshold,
+		maxAnchors:          defaultMaxAnchorsPerPage,
+	}
+	ds.SetAllowedLinkSchemes(defaultLinkSchemes)
+	return ds
+}
+
+// SetStripQueryParams configures query parameters that are removed from extracted links
+// The special value "all" removes the whole query string
+func (ds *DirectoryScanner) SetStripQueryParams(params []string) {
+	ds.stripQueryParams = make(map[string]bool, len(params))
+	for _, param := range params {
+		if strings.EqualFold(param, "all") {
+			ds.stripAllQueryArgs = true
+			continue
+		}
+		ds.stripQueryParams[param] = true
+	}
+}
+
+// SetAllowedLinkSchemes configures which schemes extracted links may have
+// Links like javascript:, mailto: or data: are dropped unless listed here
+func (ds *DirectoryScanner) SetAllowedLinkSchemes(schemes []string) {
+	ds.allowedSchemes = make(map[string]bool, len(schemes))
+	for _, scheme := range schemes {
+		ds.allowedSchemes[strings.ToLower(strings.TrimSuffix(scheme, ":"))] = true
+	}
+}
+
+// SetDirectoryIndicators adds custom listing indicators (e.g. "verzeichnis von"), matched
+// anywhere in the page case-insensitively. With replace, the built-in indicators
+// (including the title/heading phrases) are dropped and only the custom ones are used
+func (ds *DirectoryScanner) SetDirectoryIndicators(indicators []string, replace bool) {
+	custom := make([]string, 0, len(indicators))
+	for _, indicator := range indicators {
+		if indicator = strings.ToLower(strings.TrimSpace(indicator)); indicator != "" {
+			custom = append(custom, indicator)
+		}
+	}
+
+	if replace {
+		ds.directoryIndicators = custom
+		ds.headingIndicators = nil
+		return
 	}
+	ds.directoryIndicators = append(append([]string{}, defaultDirectoryIndicators...), custom...)
+}
+
+// SetListingLinkThreshold sets the number of file links above which a page without
+// indicators is treated as a listing (values <= 0 keep the default of 5)
+func (ds *DirectoryScanner) SetListingLinkThreshold(threshold int) {
+	if threshold <= 0 {
+		threshold = defaultListingLinkThreshold
+	}
+	ds.linkThreshold = threshold
+}
+
+// SetMaxConcurrentDirFetches limits the directory fetches of recursive scans in flight at once
+// The limit is shared by all hosts scanned with this scanner; 0 means unlimited
+func (ds *DirectoryScanner) SetMaxConcurrentDirFetches(limit int) {
+	if limit <= 0 {
+		ds.dirFetchSlots = nil
+		return
+	}
+	ds.dirFetchSlots = make(chan struct{}, limit)
+}
+
+// fetchDirectory fetches a subdirectory once a fetch slot is free
+// The slot is released after the fetch, before the listing is recursed into, so nested
+// fetches of the same host cannot wait on each other
+func (ds *DirectoryScanner) fetchDirectory(ctx context.Context, client HTTPClient, dirHost api.Host) (bool, string, string, error) {
+	if ds.dirFetchSlots != nil {
+		select {
+		case ds.dirFetchSlots <- struct{}{}:
+		case <-ctx.Done():
+			return false, "", "", ctx.Err()
+		}
+		defer func() { <-ds.dirFetchSlots }()
+	}
+	return client.CheckHostAndFetch(ctx, dirHost)
+}
+
+// SetMaxAnchorsPerPage sets the number of <a> tags processed per page (0 = 100000)
+// Anchors beyond the limit are ignored and a warning is logged
+func (ds *DirectoryScanner) SetMaxAnchorsPerPage(limit int) {
+	if limit <= 0 {
+		limit = defaultMaxAnchorsPerPage
+	}
+	ds.maxAnchors = limit
+}
+
+// normalizeURL removes configured cache-busting query parameters from a URL
+// This collapses duplicates like file.exe?v=1 and file.exe?v=2 and exposes the real extension
+func (ds *DirectoryScanner) normalizeURL(u *url.URL) {
+	if u.RawQuery == "" {
+		return
+	}
+	if ds.stripAllQueryArgs {
+		u.RawQuery = ""
+		return
+	}
+	if len(ds.stripQueryParams) == 0 {
+		return
+	}
+
+	query := u.Query()
+	for param := range ds.stripQueryParams {
+		query.Del(param)
+	}
+	u.RawQuery = query.Encode()
 }
 
 // ScanHost processes a host for directory listings and extracts file links
-func (ds *DirectoryScanner) ScanHost(host api.Host, htmlContent string) []string {
+// The Content-Type of the listing selects the JSON or HTML parser
+func (ds *DirectoryScanner) ScanHost(host api.Host, content string, contentType string) []string {
 	ds.logger.Debug("Scanning directory listing for host: %s", host.URL)
 
-	// Extract links from HTML content
-	links := ds.extractLinks(host.URL, htmlContent)
+	// Extract links from the listing content
+	links := ds.listingLinks(host.URL, content, contentType)
 
 	ds.logger.Debug("Directory scan found %d links for %s", len(links), host.URL)
 	return links
 }
 
 // ScanHostRecursive performs recursive directory scanning with configurable limits
-func (ds *DirectoryScanner) ScanHostRecursive(host api.Host, htmlContent string, maxDepth int, client HTTPClient, cfg *config.Config, skipCallback func(string)) []string {
+// Returns the found file URLs and the directory URLs discovered along the way
+func (ds *DirectoryScanner) ScanHostRecursive(ctx context.Context, host api.Host, content string, contentType string, maxDepth int, client HTTPClient, cfg *config.Config, skipCallback func(string)) ([]string, []string) {
 	if maxDepth <= 0 {
-		return ds.ScanHost(host, htmlContent)
+		links := ds.ScanHost(host, content, contentType)
+		return links, ds.FilterDirectories(links)
 	}
 	// Reset counter for new scan
 	atomic.StoreInt64(&ds.totalLinksCount, 0)
 	visited := make(map[string]bool)
 	allLinks := []string{}
-	ds.scanRecursive(host.URL, htmlContent, 0, maxDepth, visited, &allLinks, client, cfg, skipCallback)
-	return allLinks
+	allDirectories := []string{}
+	ds.scanRecursive(ctx, host.URL, host.VirtualHost, content, contentType, 0, maxDepth, visited, &allLinks, &allDirectories, client, cfg, skipCallback)
+	return allLinks, allDirectories
+}
+
+// FilterDirectories returns the links that point to directories
+func (ds *DirectoryScanner) FilterDirectories(links []string) []string {
+	directories := make([]string, 0, len(links)/4)
+	for _, link := range links {
+		if ds.isDirectory(link) {
+			directories = append(directories, link)
+		}
+	}
+	return directories
 }
 
 // scanRecursive performs the actual recursive scanning
-func (ds *DirectoryScanner) scanRecursive(baseURL, htmlContent string, currentDepth, maxDepth int, visited map[string]bool, allLinks *[]string, client HTTPClient, cfg *config.Config, skipCallback func(string)) {
+func (ds *DirectoryScanner) scanRecursive(ctx context.Context, baseURL, virtualHost, content, contentType string, currentDepth, maxDepth int, visited map[string]bool, allLinks *[]string, allDirectories *[]string, client HTTPClient, cfg *config.Config, skipCallback func(string)) {
 	// Check total links limit with thread-safe counter
 	currentCount := atomic.LoadInt64(&ds.totalLinksCount)
 	ds.logger.Debug("Recursion check: current count=%d, limit=%d, depth=%d, URL=%s", currentCount, cfg.MaxTotalLinks, currentDepth, baseURL)
@@ -92,7 +268,7 @@ func (ds *DirectoryScanner) scanRecursive(baseURL, htmlContent string, currentDe
 	ds.logger.Debug("Scanning depth %d: %s", currentDepth, baseURL)
 
 	// Extract links from current level
-	links := ds.extractLinks(baseURL, htmlContent)
+	links := ds.listingLinks(baseURL, content, contentType)
 	ds.logger.Debug("Found %d raw links at depth %d", len(links), currentDepth)
 
 	// Apply per-directory link limit
@@ -118,6 +294,9 @@ func (ds *DirectoryScanner) scanRecursive(baseURL, htmlContent string, currentDe
 
 	ds.logger.Debug("Link separation: %d files, %d directories", len(files), len(directories))
 
+	// Record discovered directories for the site map
+	*allDirectories = append(*allDirectories, directories...)
+
 	// Add files to results and update atomic counter
 	*allLinks = append(*allLinks, files...)
 	newCount := atomic.AddInt64(&ds.totalLinksCount, int64(len(files)))
@@ -125,24 +304,36 @@ func (ds *DirectoryScanner) scanRecursive(baseURL, htmlContent string, currentDe
 
 	// Recurse into directories if we haven't reached max depth
 	if currentDepth+1 < maxDepth {
+		// Limit how many sibling directories are followed at this level
+		if cfg.MaxBreadthDepth > 0 && len(directories) > cfg.MaxBreadthDepth {
+			ds.logger.Debug("Level has %d directories, following only %d", len(directories), cfg.MaxBreadthDepth)
+			directories = directories[:cfg.MaxBreadthDepth]
+		}
+
 		ds.logger.Debug("Planning to recurse into %d directories", len(directories))
 		for i, dirURL := range directories {
+			// Stop recursing once the scan is cancelled
+			if ctx.Err() != nil {
+				ds.logger.Debug("Scan cancelled, not recursing further")
+				return
+			}
+
 			ds.logger.Debug("Recursing into directory %d/%d: %s", i+1, len(directories), dirURL)
 
-			// Create host object for directory
-			dirHost := api.Host{URL: dirURL}
+			// Create host object for directory (keep the virtual host of the root)
+			dirHost := api.Host{URL: dirURL, VirtualHost: virtualHost}
 
-			// Fetch directory content
-			online, dirContent, err := client.CheckHostAndFetch(dirHost)
+			// Fetch directory content (waits for a slot with max_concurrent_dir_fetches)
+			online, dirContent, dirContentType, err := ds.fetchDirectory(ctx, client, dirHost)
 			if err != nil || !online {
 				ds.logger.Debug("Failed to fetch directory %s: %v", dirURL, err)
 				continue
 			}
 
 			// Check if it's a directory listing
-			if ds.IsDirectoryListing(dirContent) {
+			if ds.IsDirectoryListing(dirContent, dirContentType) {
 				ds.logger.Debug("Directory confirmed, recursing: %s", dirURL)
-				ds.scanRecursive(dirURL, dirContent, currentDepth+1, maxDepth, visited, allLinks, client, cfg, skipCallback)
+				ds.scanRecursive(ctx, dirURL, virtualHost, dirContent, dirContentType, currentDepth+1, maxDepth, visited, allLinks, allDirectories, client, cfg, skipCallback)
 			} else {
 				ds.logger.Debug("Not a directory listing, skipping: %s", dirURL)
 			}
@@ -152,6 +343,14 @@ func (ds *DirectoryScanner) scanRecursive(baseURL, htmlContent string, currentDe
 	}
 }
 
+// listingLinks extracts links from a JSON or HTML listing depending on its Content-Type
+func (ds *DirectoryScanner) listingLinks(baseURL string, content string, contentType string) []string {
+	if IsJSONContentType(contentType) {
+		return ds.extractJSONLinks(baseURL, content)
+	}
+	return ds.extractLinks(baseURL, content)
+}
+
 // extractLinks extracts file links from HTML directory listing content
 func (ds *DirectoryScanner) extractLinks(baseURLStr string, htmlContent string) []string {
 	// Pre-allocate with reasonable capacity for typical directory listings
@@ -171,39 +370,44 @@ func (ds *DirectoryScanner) extractLinks(baseURLStr string, htmlContent string)
 		return links
 	}
 
-	// Find all links in the directory listing
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+	// Find all links in the directory listing, up to max_anchors_per_page
+	anchors := doc.Find("a")
+	anchors.EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if i >= ds.maxAnchors {
+			return false
+		}
+
 		href, exists := s.Attr("href")
 		if !exists {
-			return
+			return true
 		}
 
 		// Skip parent directory links and navigation elements
 		if href == "../" || href == ".." || href == "." || href == "/" {
-			return
+			return true
 		}
 
 		// Skip Apache directory listing sort links
 		if strings.HasPrefix(href, "?C=") {
-			return
+			return true
 		}
 
 		// Skip other sort parameter links
 		if strings.HasPrefix(href, "/?sort=") {
-			return
+			return true
 		}
 
-		// Resolve relative URLs to absolute URLs
-		fileURL, err := url.Parse(href)
-		if err != nil {
-			ds.logger.Debug("Failed to parse URL: %s", href)
-			return
+		absoluteURL, ok := ds.resolveLink(baseURL, href)
+		if !ok {
+			return true
 		}
-
-		absoluteURL := baseURL.ResolveReference(fileURL).String()
 		links = append(links, absoluteURL)
 		ds.logger.Debug("Found directory link: %s", absoluteURL)
+		return true
 	})
+	if anchors.Length() > ds.maxAnchors {
+		ds.logger.Error("WARNING: %s has %d links, processed only the first %d (max_anchors_per_page)", baseURLStr, anchors.Length(), ds.maxAnchors)
+	}
 
 	if len(links) > 0 {
 		ds.logger.Info("Extracted %d links from directory index at %s", len(links), baseURLStr)
@@ -213,49 +417,114 @@ func (ds *DirectoryScanner) extractLinks(baseURLStr string, htmlContent string)
 	return links
 }
 
-// IsDirectoryListing checks if the HTML content appears to be a directory listing
-func (ds *DirectoryScanner) IsDirectoryListing(htmlContent string) bool {
-	// Check for common directory listing indicators
-	content := strings.ToLower(htmlContent)
+// resolveLink resolves a listing href against the listing URL
+// Returns false for unparsable links and links with a scheme that is not allowed
+func (ds *DirectoryScanner) resolveLink(baseURL *url.URL, href string) (string, bool) {
+	// Resolve relative URLs to absolute URLs
+	fileURL, err := url.Parse(href)
+	if err != nil {
+		ds.logger.Debug("Failed to parse URL: %s", href)
+		return "", false
+	}
+
+	resolvedURL := baseURL.ResolveReference(fileURL)
 
-	// Common directory listing patterns
-	directoryIndicators := []string{
-		"index of",
-		"directory listing",
-		"parent directory",
-		"<title>index of",
-		"apache/", // Apache directory listings
-		"nginx/",  // Nginx directory listings
+	// Skip script, mail and inline data anchors
+	if !ds.allowedSchemes[strings.ToLower(resolvedURL.Scheme)] {
+		ds.logger.Debug("Skipping link with scheme %q: %s", resolvedURL.Scheme, href)
+		return "", false
 	}
 
-	for _, indicator := range directoryIndicators {
+	ds.normalizeURL(resolvedURL)
+	return resolvedURL.String(), true
+}
+
+// IsDirectoryListing checks if the content appears to be a directory listing
+func (ds *DirectoryScanner) IsDirectoryListing(content string, contentType string) bool {
+	isListing, _ := ds.DetectDirectoryListing(content, contentType)
+	return isListing
+}
+
+// DetectDirectoryListing checks if the content appears to be a directory listing
+// JSON documents are checked for autoindex entries, everything else is treated as HTML
+// Also returns the reason for the decision: the matched indicator or the link count heuristic
+func (ds *DirectoryScanner) DetectDirectoryListing(content string, contentType string) (bool, string) {
+	if IsJSONContentType(contentType) {
+		return ds.DetectJSONListing(content)
+	}
+	return ds.detectHTMLListing(content)
+}
+
+// detectHTMLListing checks if HTML content appears to be a directory listing
+func (ds *DirectoryScanner) detectHTMLListing(htmlContent string) (bool, string) {
+	// Check for common directory listing indicators
+	content := strings.ToLower(htmlContent)
+
+	for _, indicator := range ds.directoryIndicators {
 		if strings.Contains(content, indicator) {
 			ds.logger.Debug("Directory listing detected: found indicator '%s'", indicator)
-			return true
+			return true, fmt.Sprintf("indicator '%s'", indicator)
 		}
 	}
 
-	// Check for multiple file links (heuristic)
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
 	if err != nil {
-		return false
+		return false, "unparsable HTML"
+	}
+
+	if indicator := ds.matchHeading(doc); indicator != "" {
+		ds.logger.Debug("Directory listing detected: title/heading starts with '%s'", indicator)
+		return true, fmt.Sprintf("title/heading '%s'", indicator)
 	}
 
+	// Check for multiple file links (heuristic)
 	linkCount := 0
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+	doc.Find("a").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if i >= ds.maxAnchors {
+			return false
+		}
 		href, exists := s.Attr("href")
 		if exists && href != "../" && href != ".." && href != "." && href != "/" {
 			linkCount++
 		}
+		return true
 	})
 
 	// If we have many file links, it's probably a directory
-	if linkCount > 5 {
+	if linkCount > ds.linkThreshold {
 		ds.logger.Debug("Directory listing detected: found %d file links", linkCount)
-		return true
+		return true, fmt.Sprintf("link heuristic (%d links)", linkCount)
 	}
 
-	return false
+	return false, fmt.Sprintf("no indicator, %d links", linkCount)
+}
+
+// matchHeading returns the heading indicator a title or h1 starts with, or "" if none does
+// Heading phrases only count at the start, so prose like "An index of resources" is ignored
+func (ds *DirectoryScanner) matchHeading(doc *goquery.Document) string {
+	var headings []string
+	doc.Find("title, h1").Each(func(i int, s *goquery.Selection) {
+		headings = append(headings, strings.ToLower(strings.TrimSpace(s.Text())))
+	})
+	for _, heading := range headings {
+		for _, indicator := range ds.headingIndicators {
+			if strings.HasPrefix(heading, indicator) {
+				return indicator
+			}
+		}
+	}
+	return ""
+}
+
+// HasIndexOfMarker checks for the "Index of" title used by Apache and nginx listings
+// A strong signal that holds even for very small listings
+// Uses the same title/heading rule as listing detection
+func (ds *DirectoryScanner) HasIndexOfMarker(htmlContent string) bool {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return false
+	}
+	return ds.matchHeading(doc) != ""
 }
 
 // isDirectory tries to determine if a URL points to a directory
@@ -0,0 +1,44 @@
+package scanners
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"censei/logging"
+)
+
+func TestDetectDirectoryListingFixtures(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		wantListing bool
+		wantMarker  bool
+	}{
+		{"apache.html", true, true},
+		{"nginx.html", true, true},
+		{"german.html", true, true},
+
+		// Pages that mention listing phrases in prose must not be classified as listings
+		{"blog_index_of_resources.html", false, false},
+		{"article_directory_listing_prose.html", false, false},
+		{"search_results.html", false, false},
+	}
+
+	ds := NewDirectoryScanner(logging.NewLogger())
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("testdata", "listings", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			isListing, reason := ds.DetectDirectoryListing(string(content), "text/html")
+			if isListing != tt.wantListing {
+				t.Errorf("DetectDirectoryListing() = %v (%s), want %v", isListing, reason, tt.wantListing)
+			}
+			if marker := ds.HasIndexOfMarker(string(content)); marker != tt.wantMarker {
+				t.Errorf("HasIndexOfMarker() = %v, want %v", marker, tt.wantMarker)
+			}
+		})
+	}
+}
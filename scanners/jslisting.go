@@ -0,0 +1,114 @@
+package scanners
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"censei/api"
+)
+
+// JSONClient interface for file browsers that expose a JSON API
+type JSONClient interface {
+	PostJSON(ctx context.Context, host api.Host, targetURL string, payload []byte) (string, error)
+}
+
+// JS-driven file browsers identified by characteristic markup or asset references
+const (
+	JSListingH5ai            = "h5ai"
+	JSListingFileBrowser     = "File Browser"
+	JSListingDirectoryLister = "Directory Lister"
+	JSListingApaxy           = "Apaxy"
+)
+
+// jsListingIndicators maps lowercase markers to the file browser they identify
+// Checked in order, the first match wins
+var jsListingIndicators = []struct {
+	indicator string
+	name      string
+}{
+	{"/_h5ai/", JSListingH5ai},
+	{"window.filebrowser", JSListingFileBrowser},
+	{"<title>file browser</title>", JSListingFileBrowser},
+	{"directory lister", JSListingDirectoryLister},
+	{"directorylister", JSListingDirectoryLister},
+	{"apaxy", JSListingApaxy},
+}
+
+// DetectJSListing checks if the HTML belongs to a file browser that renders its listing via JavaScript
+// Returns the name of the detected file browser or an empty string
+func (ds *DirectoryScanner) DetectJSListing(htmlContent string) string {
+	content := strings.ToLower(htmlContent)
+
+	for _, entry := range jsListingIndicators {
+		if strings.Contains(content, entry.indicator) {
+			ds.logger.Debug("JS file browser detected: found indicator '%s' (%s)", entry.indicator, entry.name)
+			return entry.name
+		}
+	}
+	return ""
+}
+
+// h5aiResponse is the relevant part of the h5ai JSON API response
+type h5aiResponse struct {
+	Items []struct {
+		Href string `json:"href"`
+	} `json:"items"`
+}
+
+// ScanH5ai fetches the listing of an h5ai file browser via its JSON API
+// Returns absolute file URLs below the host URL
+func (ds *DirectoryScanner) ScanH5ai(ctx context.Context, host api.Host, client JSONClient) ([]string, error) {
+	baseURL, err := url.Parse(host.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse host URL: %w", err)
+	}
+
+	currentPath := baseURL.Path
+	if currentPath == "" {
+		currentPath = "/"
+	}
+
+	apiURL := baseURL.ResolveReference(&url.URL{Path: "/_h5ai/public/index.php"}).String()
+	payload, err := json.Marshal(map[string]interface{}{
+		"action": "get",
+		"items": map[string]interface{}{
+			"href": currentPath,
+			"what": 1,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build h5ai request: %w", err)
+	}
+
+	ds.logger.Debug("Fetching h5ai listing from %s for path %s", apiURL, currentPath)
+	body, err := client.PostJSON(ctx, host, apiURL, payload)
+	if err != nil {
+		return nil, fmt.Errorf("h5ai API request failed: %w", err)
+	}
+
+	var response h5aiResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse h5ai response: %w", err)
+	}
+
+	links := make([]string, 0, len(response.Items))
+	for _, item := range response.Items {
+		// Directories end with "/", and the response also contains parent folders
+		if item.Href == "" || strings.HasSuffix(item.Href, "/") || !strings.HasPrefix(item.Href, currentPath) {
+			continue
+		}
+
+		itemURL, err := url.Parse(item.Href)
+		if err != nil {
+			ds.logger.Debug("Failed to parse h5ai item: %s", item.Href)
+			continue
+		}
+		links = append(links, baseURL.ResolveReference(itemURL).String())
+	}
+
+	ds.logger.Info("Extracted %d links from h5ai API at %s", len(links), host.URL)
+	return links, nil
+}
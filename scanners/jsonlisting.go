@@ -0,0 +1,153 @@
+package scanners
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// jsonListingContainers are object keys that wrap the entry array of a JSON listing
+var jsonListingContainers = []string{"files", "items", "entries", "contents", "children"}
+
+// jsonLinkKeys are entry keys holding an already URL-encoded link, preferred over names
+var jsonLinkKeys = []string{"url", "href"}
+
+// jsonNameKeys are entry keys holding a plain file name or path
+var jsonNameKeys = []string{"name", "path", "key", "filename"}
+
+// jsonEntry is a file or directory of a JSON listing
+type jsonEntry struct {
+	href  string // URL-encoded link relative to the listing
+	isDir bool
+}
+
+// IsJSONContentType checks if a Content-Type announces a JSON document
+func IsJSONContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// DetectJSONListing checks if a JSON document is a directory listing
+// Recognizes autoindex shapes like nginx (autoindex_format json) and Caddy (browse with Accept: application/json)
+func (ds *DirectoryScanner) DetectJSONListing(content string) (bool, string) {
+	entries, err := parseJSONListing(content)
+	if err != nil {
+		return false, "unparsable JSON"
+	}
+	if len(entries) == 0 {
+		return false, "JSON without listing entries"
+	}
+	return true, fmt.Sprintf("JSON listing (%d entries)", len(entries))
+}
+
+// extractJSONLinks extracts file and directory links from a JSON directory listing
+// Directory links end with "/" like in HTML listings
+func (ds *DirectoryScanner) extractJSONLinks(baseURLStr string, content string) []string {
+	entries, err := parseJSONListing(content)
+	if err != nil {
+		ds.logger.Error("Failed to parse JSON listing: %v", err)
+		return []string{}
+	}
+
+	baseURL, err := url.Parse(baseURLStr)
+	if err != nil {
+		ds.logger.Error("Failed to parse base URL: %v", err)
+		return []string{}
+	}
+
+	links := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		href := entry.href
+		if entry.isDir && !strings.HasSuffix(href, "/") {
+			href += "/"
+		}
+		if link, ok := ds.resolveLink(baseURL, href); ok {
+			links = append(links, link)
+			ds.logger.Debug("Found JSON listing link: %s", link)
+		}
+	}
+
+	if len(links) > 0 {
+		ds.logger.Info("Extracted %d links from JSON listing at %s", len(links), baseURLStr)
+	} else {
+		ds.logger.Debug("Extracted 0 links from JSON listing at %s", baseURLStr)
+	}
+	return links
+}
+
+// parseJSONListing reads the entries of a JSON listing: an array of entry objects
+// (or plain names), optionally wrapped in an object under a key like "files" or "items"
+func parseJSONListing(content string) ([]jsonEntry, error) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(content), &document); err != nil {
+		return nil, err
+	}
+
+	items, ok := document.([]interface{})
+	if object, isObject := document.(map[string]interface{}); isObject {
+		for _, key := range jsonListingContainers {
+			if items, ok = object[key].([]interface{}); ok {
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	entries := make([]jsonEntry, 0, len(items))
+	for _, item := range items {
+		if entry, ok := parseJSONEntry(item); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// parseJSONEntry reads one listing entry, skipping parent and current directory entries
+func parseJSONEntry(item interface{}) (jsonEntry, bool) {
+	var entry jsonEntry
+
+	switch value := item.(type) {
+	case string:
+		entry.href = (&url.URL{Path: value}).String()
+		entry.isDir = strings.HasSuffix(value, "/")
+
+	case map[string]interface{}:
+		for _, key := range jsonLinkKeys {
+			if link, ok := value[key].(string); ok && link != "" {
+				entry.href = link
+				break
+			}
+		}
+		if entry.href == "" {
+			for _, key := range jsonNameKeys {
+				if name, ok := value[key].(string); ok && name != "" {
+					entry.href = (&url.URL{Path: name}).String()
+					break
+				}
+			}
+		}
+
+		entryType, _ := value["type"].(string)
+		switch strings.ToLower(entryType) {
+		case "directory", "dir", "folder":
+			entry.isDir = true
+		}
+		for _, key := range []string{"is_dir", "isDir", "directory"} {
+			if isDir, ok := value[key].(bool); ok && isDir {
+				entry.isDir = true
+			}
+		}
+
+	default:
+		return entry, false
+	}
+
+	switch strings.TrimPrefix(entry.href, "./") {
+	case "", ".", "./", "..", "../", "/":
+		return entry, false
+	}
+	return entry, true
+}
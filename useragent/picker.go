@@ -0,0 +1,63 @@
+package useragent
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net/url"
+)
+
+// Default is the User-Agent sent when no custom agent or pool is configured
+const Default = "Mozilla/5.0 (compatible; CenseiBot/1.0)"
+
+// Picker selects the User-Agent for outgoing requests
+// With an empty pool the single configured (or default) agent is always used
+type Picker struct {
+	userAgent string
+	pool      []string
+	perHost   bool
+}
+
+// NewPicker creates a User-Agent picker
+// perHost makes the choice stable for a host instead of random per request
+func NewPicker(userAgent string, pool []string, perHost bool) *Picker {
+	if userAgent == "" {
+		userAgent = Default
+	}
+
+	// Drop empty entries so a sloppy config never sends a blank agent
+	agents := make([]string, 0, len(pool))
+	for _, agent := range pool {
+		if agent != "" {
+			agents = append(agents, agent)
+		}
+	}
+
+	return &Picker{
+		userAgent: userAgent,
+		pool:      agents,
+		perHost:   perHost,
+	}
+}
+
+// Pick returns the User-Agent to use for a request to the given URL
+func (p *Picker) Pick(requestURL string) string {
+	if p == nil {
+		return Default
+	}
+	if len(p.pool) == 0 {
+		return p.userAgent
+	}
+
+	if p.perHost {
+		// Hash the host so every request to it uses the same agent
+		host := requestURL
+		if parsedURL, err := url.Parse(requestURL); err == nil && parsedURL.Host != "" {
+			host = parsedURL.Host
+		}
+		hash := fnv.New32a()
+		hash.Write([]byte(host))
+		return p.pool[hash.Sum32()%uint32(len(p.pool))]
+	}
+
+	return p.pool[rand.Intn(len(p.pool))]
+}